@@ -1,7 +1,6 @@
 package calculator
 
 import (
-	"fmt"
 	"math"
 )
 
@@ -20,7 +19,7 @@ func Multiply(a, b float64) float64 {
 
 func Divide(a, b float64) (float64, error) {
 	if b == 0 {
-		return 0, fmt.Errorf("division by zero")
+		return 0, domainError(a/b, "division by zero")
 	}
 	return a / b, nil
 }
@@ -31,7 +30,7 @@ func Power(base, exponent float64) float64 {
 
 func Modulus(a, b float64) (float64, error) {
 	if b == 0 {
-		return 0, fmt.Errorf("modulus by zero")
+		return 0, domainError(math.NaN(), "modulus by zero")
 	}
 	return math.Mod(a, b), nil
 }
@@ -40,14 +39,48 @@ func Percentage(value, percent float64) float64 {
 	return value * percent / 100
 }
 
+// Factorial returns n!, using Γ(n+1) for non-integers. Results beyond
+// 170! overflow to +Inf.
 func Factorial(n float64) (float64, error) {
-	if n < 0 || n != math.Trunc(n) {
-		return 0, fmt.Errorf("factorial undefined for non-integer or negative numbers")
+	if n < 0 && n == math.Trunc(n) {
+		return 0, domainError(math.NaN(), "factorial undefined for negative integers")
+	}
+	if n != math.Trunc(n) {
+		return math.Gamma(n + 1), nil
+	}
+	if n > 170 {
+		return math.Inf(1), nil
 	}
 
 	result := 1.0
-	for i := 2.0; i <= n; i++ {
+	for i := 2.0; i <= n && !math.IsInf(result, 1); i++ {
 		result *= i
 	}
 	return result, nil
 }
+
+func Min(values ...float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	minVal := values[0]
+	for _, v := range values[1:] {
+		if v < minVal {
+			minVal = v
+		}
+	}
+	return minVal
+}
+
+func Max(values ...float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	maxVal := values[0]
+	for _, v := range values[1:] {
+		if v > maxVal {
+			maxVal = v
+		}
+	}
+	return maxVal
+}
@@ -0,0 +1,305 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// MaxBigDigits bounds the precision accepted by NewBigDomain
+const MaxBigDigits = 10000
+
+// bigExtraBits are carried beyond the requested digits so that the last
+// digit stays correctly rounded after a chain of operations. Comparisons
+// ignore them.
+const bigExtraBits = 16
+
+// maxBigExp bounds the binary exponent of a result, about 10^315000, since
+// printing takes time that grows with the exponent: 2^(10^9) is computed
+// at once but would take minutes to print
+const maxBigExp = 1 << 20
+
+// BigFloat is an arbitrary-precision value produced by BigDomain
+type BigFloat struct {
+	x      *big.Float
+	digits int // significant digits shown by String
+}
+
+// NewBigFloat wraps x, which must not be modified afterwards, for display
+// with the given number of significant digits
+func NewBigFloat(x *big.Float, digits int) BigFloat {
+	return BigFloat{x: x, digits: digits}
+}
+
+// Big returns a copy of the underlying big.Float
+func (v BigFloat) Big() *big.Float {
+	return new(big.Float).Copy(v.x)
+}
+
+// Digits returns the number of significant digits shown by String
+func (v BigFloat) Digits() int {
+	return v.digits
+}
+
+func (v BigFloat) Float64() float64 {
+	f, _ := v.x.Float64()
+	return f
+}
+
+func (v BigFloat) String() string {
+	return v.x.Text('g', v.digits)
+}
+
+// BigDomain evaluates with big.Float arithmetic carrying a fixed number of
+// significant decimal digits. Constants and elementary functions are
+// computed to the same precision. Functions registered without a BigFunc,
+// or whose BigFunc returns ErrInexact, fall back to float64 and return a
+// Float. Results with a magnitude beyond about 10^±315000 are an error.
+type BigDomain struct {
+	digits int
+	prec   uint // bits
+}
+
+// NewBigDomain creates a domain working to the given number of significant
+// digits, clamped to [1, MaxBigDigits]
+func NewBigDomain(digits int) *BigDomain {
+	digits = max(1, min(digits, MaxBigDigits))
+
+	prec := uint(math.Ceil(float64(digits)*math.Log2(10))) + bigExtraBits
+	return &BigDomain{digits: digits, prec: prec}
+}
+
+func (d *BigDomain) Name() string {
+	return "big"
+}
+
+// Digits returns the number of significant digits the domain works to
+func (d *BigDomain) Digits() int {
+	return d.digits
+}
+
+// Prec returns the working precision in bits
+func (d *BigDomain) Prec() uint {
+	return d.prec
+}
+
+// integer returns n with every digit, even beyond the domain's precision
+func (d *BigDomain) integer(n *big.Int) Value {
+	digits := len(new(big.Int).Abs(n).String())
+	prec := max(d.prec, uint(n.BitLen()))
+	return NewBigFloat(newBig(prec).SetInt(n), max(d.digits, digits))
+}
+
+func (d *BigDomain) value(x *big.Float) (Value, error) {
+	if x.IsInf() {
+		return nil, fmt.Errorf("result overflows")
+	}
+	if exp := x.MantExp(nil); exp > maxBigExp {
+		return nil, fmt.Errorf("result too large for big mode")
+	} else if exp < -maxBigExp {
+		return nil, fmt.Errorf("result too small for big mode")
+	}
+	return NewBigFloat(x, d.digits), nil
+}
+
+// big converts any value to a big.Float at the domain's precision
+func (d *BigDomain) big(x Value) (*big.Float, error) {
+	switch v := x.(type) {
+	case BigFloat:
+		return newBig(d.prec).Set(v.x), nil
+	case integral:
+		return newBig(d.prec).SetInt(v.Int()), nil
+	case Rational:
+		return newBig(d.prec).SetRat(v.r), nil
+	case RadixNumber:
+		return newBig(d.prec).SetRat(v.r), nil
+	default:
+		f := x.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%v has no arbitrary-precision value", f)
+		}
+		return newBig(d.prec).SetFloat64(f), nil
+	}
+}
+
+func (d *BigDomain) Literal(text string) (Value, error) {
+	x, ok := newBig(d.prec).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", text)
+	}
+	return d.value(x)
+}
+
+func (d *BigDomain) Constant(name string) (Value, bool) {
+	switch name {
+	case "pi", "π":
+		return NewBigFloat(BigPi(d.prec), d.digits), true
+	case "e":
+		return NewBigFloat(BigE(d.prec), d.digits), true
+	}
+	return nil, false
+}
+
+func (d *BigDomain) Convert(x Value) (Value, error) {
+	b, err := d.big(x)
+	if err != nil {
+		return nil, err
+	}
+	return d.value(b)
+}
+
+func (d *BigDomain) Bool(b bool) Value {
+	return NewBigFloat(bigInt(int64(FromBool(b)), d.prec), d.digits)
+}
+
+func (d *BigDomain) Truthy(x Value) bool {
+	b, err := d.big(x)
+	return err == nil && b.Sign() != 0
+}
+
+func (d *BigDomain) Negate(x Value) (Value, error) {
+	b, err := d.big(x)
+	if err != nil {
+		return nil, err
+	}
+	return d.value(b.Neg(b))
+}
+
+func (d *BigDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	a, err := d.big(x)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.big(y)
+	if err != nil {
+		return nil, err
+	}
+
+	z := newBig(d.prec)
+	switch op {
+	case "+":
+		return d.value(z.Add(a, b))
+	case "-":
+		return d.value(z.Sub(a, b))
+	case "*":
+		return d.value(z.Mul(a, b))
+	case "/":
+		if b.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return d.value(z.Quo(a, b))
+	case "%":
+		if b.Sign() == 0 {
+			return nil, fmt.Errorf("modulus by zero")
+		}
+		// Like math.Mod, the result has the sign of a
+		q := newBig(d.prec+uint(max(0, exponent(a)-exponent(b)))).Quo(a, b)
+		i, _ := q.Int(nil)
+		q.SetInt(i)
+		return d.value(z.Sub(a, q.Mul(q, b)))
+	case "^":
+		result, err := BigPow(ctx, a, b, d.prec)
+		if err != nil {
+			return nil, err
+		}
+		return d.value(result)
+	case "<", "<=", ">", ">=", "==", "!=":
+		result, err := Compare(op, float64(d.cmp(a, b)), 0)
+		if err != nil {
+			return nil, err
+		}
+		return d.Bool(Truthy(result)), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+// cmp compares a and b, treating them as equal when they differ only in the
+// extra bits, so that 0.1 + 0.2 == 0.3 holds as it does on paper
+func (d *BigDomain) cmp(a, b *big.Float) int {
+	diff := newBig(d.prec).Sub(a, b)
+	if diff.Sign() == 0 {
+		return 0
+	}
+	scale := max(exponent(a), exponent(b))
+	if exponent(diff) <= scale-int(d.prec-bigExtraBits) {
+		return 0
+	}
+	return diff.Sign()
+}
+
+// Factorial is exact for integers, showing every digit of the result, and
+// uses gamma otherwise
+func (d *BigDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	b, err := d.big(x)
+	if err != nil {
+		return nil, err
+	}
+	if !b.IsInt() {
+		g, err := BigGamma(b.Add(b, bigInt(1, d.prec)), d.prec)
+		if errors.Is(err, ErrInexact) {
+			return FloatDomain{}.Factorial(ctx, x)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return d.value(g)
+	}
+
+	n, ok := exactInt(b)
+	if !ok {
+		return nil, fmt.Errorf("factorial argument too large")
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("factorial undefined for negative integers")
+	}
+	return d.integer(FactorialInt(n)), nil
+}
+
+func (d *BigDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	b, err := d.big(x)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := exactInt(b)
+	if !ok && b.IsInt() {
+		return nil, fmt.Errorf("double factorial argument too large")
+	}
+	if !ok || n < -1 {
+		return nil, fmt.Errorf("double factorial undefined for non-integers and integers below -1")
+	}
+	return d.integer(doubleFactorialInt(n)), nil
+}
+
+func (d *BigDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		if n, isInt := v.(Integer); isInt {
+			return d.integer(n.n), nil
+		}
+		return v, err
+	}
+	impl, ok := ImplementationOf[BigFunc](f)
+	if !ok {
+		return FloatDomain{}.Call(ctx, f, args)
+	}
+
+	bigs := make([]*big.Float, len(args))
+	for i, arg := range args {
+		b, err := d.big(arg)
+		if err != nil {
+			return nil, err
+		}
+		bigs[i] = b
+	}
+
+	result, err := impl(ctx, bigs, d.prec)
+	if errors.Is(err, ErrInexact) {
+		return FloatDomain{}.Call(ctx, f, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.value(result)
+}
@@ -0,0 +1,684 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+)
+
+// Arbitrary-precision versions of the elementary functions. Each takes the
+// precision of the result in bits, works internally with guard bits and
+// rounds once at the end.
+
+// guardBits is the extra working precision used inside series evaluations
+const guardBits = 64
+
+// BigFunc is the arbitrary-precision implementation of a function
+type BigFunc func(ctx context.Context, args []*big.Float, prec uint) (*big.Float, error)
+
+func (BigFunc) implementation() {}
+
+func newBig(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+func bigInt(n int64, prec uint) *big.Float {
+	return newBig(prec).SetInt64(n)
+}
+
+// bigHalf returns 0.5 at the given precision
+func bigHalf(prec uint) *big.Float {
+	return newBig(prec).SetFloat64(0.5)
+}
+
+// negligible reports whether adding term to sum can no longer change sum at
+// the given precision
+func negligible(term, sum *big.Float, prec uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	if sum.Sign() == 0 {
+		return false
+	}
+	return term.MantExp(nil) < sum.MantExp(nil)-int(prec)-1
+}
+
+// cancelled returns the context's error on every 16th term n of a series,
+// so that a long series stops soon after the context is done
+func cancelled(ctx context.Context, n int64) error {
+	if n%16 != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// cmpAbs compares |x| and |y|
+func cmpAbs(x, y *big.Float) int {
+	return new(big.Float).Abs(x).Cmp(new(big.Float).Abs(y))
+}
+
+// exponent returns the binary exponent of x, so that 2^(e-1) <= |x| < 2^e
+func exponent(x *big.Float) int {
+	return x.MantExp(nil)
+}
+
+var (
+	constMu    sync.Mutex
+	constCache = make(map[string]*big.Float)
+)
+
+// cachedConst returns a constant computed by compute at precision prec,
+// reusing earlier results computed at the same or higher precision. The
+// lock is not held while computing, since constants depend on each other.
+// A computation stopped by ctx is not cached.
+func cachedConst(ctx context.Context, name string, prec uint, compute func(context.Context, uint) (*big.Float, error)) (*big.Float, error) {
+	constMu.Lock()
+	c, ok := constCache[name]
+	constMu.Unlock()
+
+	if ok && c.Prec() >= prec {
+		return newBig(prec).Set(c), nil
+	}
+
+	c, err := compute(ctx, prec+guardBits)
+	if err != nil {
+		return nil, err
+	}
+
+	constMu.Lock()
+	if old, ok := constCache[name]; !ok || old.Prec() < c.Prec() {
+		constCache[name] = c
+	}
+	constMu.Unlock()
+
+	return newBig(prec).Set(c), nil
+}
+
+// BigPi returns π rounded to prec bits
+func BigPi(prec uint) *big.Float {
+	pi, _ := bigPi(context.Background(), prec)
+	return pi
+}
+
+// bigPi is BigPi for use inside other functions, stopping when ctx is done
+func bigPi(ctx context.Context, prec uint) (*big.Float, error) {
+	return cachedConst(ctx, "pi", prec, func(ctx context.Context, wp uint) (*big.Float, error) {
+		// Machin's formula: π = 16·atan(1/5) - 4·atan(1/239)
+		a, err := atanInv(ctx, 5, wp)
+		if err != nil {
+			return nil, err
+		}
+		b, err := atanInv(ctx, 239, wp)
+		if err != nil {
+			return nil, err
+		}
+		a.Mul(a, bigInt(16, wp))
+		b.Mul(b, bigInt(4, wp))
+		return a.Sub(a, b), nil
+	})
+}
+
+// BigE returns e rounded to prec bits
+func BigE(prec uint) *big.Float {
+	e, _ := cachedConst(context.Background(), "e", prec, func(ctx context.Context, wp uint) (*big.Float, error) {
+		return BigExp(ctx, bigInt(1, wp), wp)
+	})
+	return e
+}
+
+// bigLn2 returns ln 2 rounded to prec bits
+func bigLn2(ctx context.Context, prec uint) (*big.Float, error) {
+	return cachedConst(ctx, "ln2", prec, func(ctx context.Context, wp uint) (*big.Float, error) {
+		// ln 2 = 2·atanh(1/3)
+		third := newBig(wp).Quo(bigInt(1, wp), bigInt(3, wp))
+		return atanhSeries(ctx, third, wp)
+	})
+}
+
+// bigLn10 returns ln 10 rounded to prec bits
+func bigLn10(ctx context.Context, prec uint) (*big.Float, error) {
+	return cachedConst(ctx, "ln10", prec, func(ctx context.Context, wp uint) (*big.Float, error) {
+		return BigLog(ctx, bigInt(10, wp), wp)
+	})
+}
+
+// atanInv returns atan(1/n) by its Taylor series
+func atanInv(ctx context.Context, n int64, prec uint) (*big.Float, error) {
+	term := newBig(prec).Quo(bigInt(1, prec), bigInt(n, prec))
+	sum := newBig(prec).Set(term)
+	n2 := bigInt(n*n, prec)
+	t := newBig(prec)
+
+	for k := int64(1); ; k++ {
+		if err := cancelled(ctx, k); err != nil {
+			return nil, err
+		}
+		term.Quo(term, n2)
+		t.Quo(term, bigInt(2*k+1, prec))
+		if negligible(t, sum, prec) {
+			return sum, nil
+		}
+		if k%2 == 1 {
+			sum.Sub(sum, t)
+		} else {
+			sum.Add(sum, t)
+		}
+	}
+}
+
+// atanhSeries returns 2·atanh(z) = ln((1+z)/(1-z)) for small |z|
+func atanhSeries(ctx context.Context, z *big.Float, prec uint) (*big.Float, error) {
+	z2 := newBig(prec).Mul(z, z)
+	term := newBig(prec).Set(z)
+	sum := newBig(prec).Set(z)
+	t := newBig(prec)
+
+	for k := int64(1); ; k++ {
+		if err := cancelled(ctx, k); err != nil {
+			return nil, err
+		}
+		term.Mul(term, z2)
+		t.Quo(term, bigInt(2*k+1, prec))
+		if negligible(t, sum, prec) {
+			break
+		}
+		sum.Add(sum, t)
+	}
+	return sum.Mul(sum, bigInt(2, prec)), nil
+}
+
+// BigSqrt returns the square root of x
+func BigSqrt(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() < 0 {
+		return nil, fmt.Errorf("square root of negative number")
+	}
+	if x.Sign() == 0 {
+		return newBig(prec), nil
+	}
+	return newBig(prec).Sqrt(x), nil
+}
+
+// BigAbs returns the absolute value of x
+func BigAbs(x *big.Float, prec uint) *big.Float {
+	return newBig(prec).Abs(x)
+}
+
+// BigCbrt returns the cube root of x
+func BigCbrt(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return newBig(prec), nil
+	}
+
+	wp := prec + guardBits
+	a := newBig(wp).Abs(x)
+	lg, err := BigLog(ctx, a, wp)
+	if err != nil {
+		return nil, err
+	}
+	y, err := BigExp(ctx, lg.Quo(lg, bigInt(3, wp)), wp)
+	if err != nil {
+		return nil, err
+	}
+
+	// One Newton step, y -= (y³ - a) / 3y², cleans up the last bits
+	y3 := newBig(wp).Mul(y, y)
+	d := newBig(wp).Mul(y3, bigInt(3, wp))
+	y3.Mul(y3, y)
+	y3.Sub(y3, a)
+	y.Sub(y, y3.Quo(y3, d))
+
+	if x.Sign() < 0 {
+		y.Neg(y)
+	}
+	return newBig(prec).Set(y), nil
+}
+
+// BigExp returns e^x
+func BigExp(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return bigInt(1, prec), nil
+	}
+	if exponent(x) > 40 {
+		if x.Sign() < 0 {
+			return newBig(prec), nil
+		}
+		return nil, fmt.Errorf("exp overflow")
+	}
+
+	// e^x = (e^(x/2^k))^(2^k) with |x/2^k| < 2^-8
+	k := exponent(x) + 8
+	if k < 0 {
+		k = 0
+	}
+	wp := prec + guardBits + uint(k)
+	r := newBig(wp).SetMantExp(x, -k)
+
+	sum := bigInt(1, wp)
+	term := bigInt(1, wp)
+	for n := int64(1); ; n++ {
+		if err := cancelled(ctx, n); err != nil {
+			return nil, err
+		}
+		term.Mul(term, r)
+		term.Quo(term, bigInt(n, wp))
+		if negligible(term, sum, wp) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+
+	for i := 0; i < k; i++ {
+		sum.Mul(sum, sum)
+	}
+	if sum.IsInf() {
+		return nil, fmt.Errorf("exp overflow")
+	}
+	return newBig(prec).Set(sum), nil
+}
+
+// BigLog returns the natural logarithm of x
+func BigLog(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, fmt.Errorf("logarithm undefined for non-positive numbers")
+	}
+
+	// x = m·2^e; keeping m in [√½, √2) avoids cancellation near x = 1
+	wp := prec + guardBits
+	m := newBig(wp)
+	e := x.MantExp(m)
+	if m.Cmp(big.NewFloat(math.Sqrt2/2)) < 0 {
+		m.SetMantExp(m, 1)
+		e--
+	}
+
+	// ln m = 2·atanh((m-1)/(m+1))
+	num := newBig(wp).Sub(m, bigInt(1, wp))
+	den := newBig(wp).Add(m, bigInt(1, wp))
+	result, err := atanhSeries(ctx, num.Quo(num, den), wp)
+	if err != nil {
+		return nil, err
+	}
+
+	if e != 0 {
+		ln2, err := bigLn2(ctx, wp)
+		if err != nil {
+			return nil, err
+		}
+		result.Add(result, ln2.Mul(ln2, bigInt(int64(e), wp)))
+	}
+	return newBig(prec).Set(result), nil
+}
+
+// BigLog10 returns the base-10 logarithm of x
+func BigLog10(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, fmt.Errorf("log10 undefined for non-positive numbers")
+	}
+	wp := prec + guardBits
+	ln, err := BigLog(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	ln10, err := bigLn10(ctx, wp)
+	if err != nil {
+		return nil, err
+	}
+	return newBig(prec).Quo(ln, ln10), nil
+}
+
+// BigPow returns x^y. Integer exponents are computed by repeated squaring.
+func BigPow(ctx context.Context, x, y *big.Float, prec uint) (*big.Float, error) {
+	if y.IsInt() && exponent(y) < 32 {
+		n, _ := y.Int64()
+		return bigPowInt(x, n, prec)
+	}
+
+	switch x.Sign() {
+	case -1:
+		return nil, fmt.Errorf("negative base with non-integer exponent")
+	case 0:
+		if y.Sign() < 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return newBig(prec), nil
+	}
+
+	// x^y = e^(y·ln x)
+	wp := prec + guardBits
+	ln, err := BigLog(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	result, err := BigExp(ctx, ln.Mul(ln, y), wp)
+	if err != nil {
+		return nil, err
+	}
+	return newBig(prec).Set(result), nil
+}
+
+func bigPowInt(x *big.Float, n int64, prec uint) (*big.Float, error) {
+	if x.Sign() == 0 && n < 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+
+	wp := prec + guardBits
+	result := bigInt(1, wp)
+	base := newBig(wp).Set(x)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+	}
+
+	if neg {
+		result.Quo(bigInt(1, wp), result)
+	}
+	if result.IsInf() {
+		return nil, fmt.Errorf("power overflow")
+	}
+	return newBig(prec).Set(result), nil
+}
+
+// reduceAngle returns x reduced to [-π, π]
+func reduceAngle(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	// Enough bits of π to cancel every integer bit of x
+	wp := prec + guardBits
+	if e := exponent(x); e > 0 {
+		wp += uint(e)
+	}
+
+	pi, err := bigPi(ctx, wp)
+	if err != nil {
+		return nil, err
+	}
+	twoPi := newBig(wp).SetMantExp(pi, 1)
+
+	r := newBig(wp).Set(x)
+	if cmpAbs(r, pi) <= 0 {
+		return r, nil
+	}
+
+	q := newBig(wp).Quo(r, twoPi)
+	n := bigFloor(q.Add(q, bigHalf(wp)))
+	return r.Sub(r, n.Mul(n, twoPi)), nil
+}
+
+// BigSin returns the sine of x (radians)
+func BigSin(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	r, err := reduceAngle(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	r2 := newBig(wp).Mul(r, r)
+
+	// sin r = r - r³/3! + r⁵/5! - ...
+	term := newBig(wp).Set(r)
+	sum := newBig(wp).Set(r)
+	for n := int64(1); ; n++ {
+		if err := cancelled(ctx, n); err != nil {
+			return nil, err
+		}
+		term.Mul(term, r2)
+		term.Quo(term, bigInt((2*n)*(2*n+1), wp))
+		term.Neg(term)
+		if negligible(term, sum, wp) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return newBig(prec).Set(sum), nil
+}
+
+// BigCos returns the cosine of x (radians)
+func BigCos(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	r, err := reduceAngle(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	r2 := newBig(wp).Mul(r, r)
+
+	// cos r = 1 - r²/2! + r⁴/4! - ...
+	term := bigInt(1, wp)
+	sum := bigInt(1, wp)
+	for n := int64(1); ; n++ {
+		if err := cancelled(ctx, n); err != nil {
+			return nil, err
+		}
+		term.Mul(term, r2)
+		term.Quo(term, bigInt((2*n-1)*(2*n), wp))
+		term.Neg(term)
+		if negligible(term, sum, wp) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return newBig(prec).Set(sum), nil
+}
+
+// BigTan returns the tangent of x (radians)
+func BigTan(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	c, err := BigCos(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	if c.Sign() == 0 {
+		return nil, fmt.Errorf("tangent undefined")
+	}
+	s, err := BigSin(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	return newBig(prec).Quo(s, c), nil
+}
+
+// BigAtan returns the arctangent of x in radians
+func BigAtan(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return newBig(prec), nil
+	}
+
+	wp := prec + guardBits
+	a := newBig(wp).Abs(x)
+
+	// atan a = π/2 - atan(1/a) for a > 1
+	invert := a.Cmp(bigInt(1, wp)) > 0
+	if invert {
+		a.Quo(bigInt(1, wp), a)
+	}
+
+	// atan a = 2·atan(a / (1 + √(1 + a²))) until a is small
+	halvings := 0
+	for exponent(a) > -4 {
+		s := newBig(wp).Mul(a, a)
+		s.Add(s, bigInt(1, wp))
+		s.Sqrt(s)
+		s.Add(s, bigInt(1, wp))
+		a.Quo(a, s)
+		halvings++
+	}
+
+	a2 := newBig(wp).Mul(a, a)
+	term := newBig(wp).Set(a)
+	sum := newBig(wp).Set(a)
+	t := newBig(wp)
+	for n := int64(1); ; n++ {
+		if err := cancelled(ctx, n); err != nil {
+			return nil, err
+		}
+		term.Mul(term, a2)
+		term.Neg(term)
+		t.Quo(term, bigInt(2*n+1, wp))
+		if negligible(t, sum, wp) {
+			break
+		}
+		sum.Add(sum, t)
+	}
+	sum.SetMantExp(sum, halvings)
+
+	if invert {
+		halfPi, err := bigPi(ctx, wp)
+		if err != nil {
+			return nil, err
+		}
+		halfPi.SetMantExp(halfPi, -1)
+		sum.Sub(halfPi, sum)
+	}
+	if x.Sign() < 0 {
+		sum.Neg(sum)
+	}
+	return newBig(prec).Set(sum), nil
+}
+
+// BigAsin returns the arcsine of x in radians
+func BigAsin(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	one := bigInt(1, wp)
+
+	switch cmpAbs(x, one) {
+	case 1:
+		return nil, fmt.Errorf("asin input must be between -1 and 1")
+	case 0:
+		halfPi, err := bigPi(ctx, prec)
+		if err != nil {
+			return nil, err
+		}
+		halfPi.SetMantExp(halfPi, -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi, nil
+	}
+
+	// asin x = atan(x / √(1 - x²))
+	d := newBig(wp).Mul(x, x)
+	d.Sub(one, d)
+	d.Sqrt(d)
+	return BigAtan(ctx, d.Quo(x, d), prec)
+}
+
+// BigAcos returns the arccosine of x in radians
+func BigAcos(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	if cmpAbs(x, big.NewFloat(1)) > 0 {
+		return nil, fmt.Errorf("acos input must be between -1 and 1")
+	}
+
+	wp := prec + guardBits
+	asin, err := BigAsin(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	halfPi, err := bigPi(ctx, wp)
+	if err != nil {
+		return nil, err
+	}
+	halfPi.SetMantExp(halfPi, -1)
+	return newBig(prec).Sub(halfPi, asin), nil
+}
+
+// BigSinh returns the hyperbolic sine of x
+func BigSinh(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+
+	// The Taylor series avoids cancellation in e^x - e^-x for small x
+	if exponent(x) <= 0 {
+		x2 := newBig(wp).Mul(x, x)
+		term := newBig(wp).Set(x)
+		sum := newBig(wp).Set(x)
+		for n := int64(1); ; n++ {
+			if err := cancelled(ctx, n); err != nil {
+				return nil, err
+			}
+			term.Mul(term, x2)
+			term.Quo(term, bigInt((2*n)*(2*n+1), wp))
+			if negligible(term, sum, wp) {
+				break
+			}
+			sum.Add(sum, term)
+		}
+		return newBig(prec).Set(sum), nil
+	}
+
+	ex, emx, err := bigExpPair(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	ex.Sub(ex, emx)
+	return newBig(prec).SetMantExp(ex, -1), nil
+}
+
+// BigCosh returns the hyperbolic cosine of x
+func BigCosh(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	ex, emx, err := bigExpPair(ctx, x, prec+guardBits)
+	if err != nil {
+		return nil, err
+	}
+	ex.Add(ex, emx)
+	return newBig(prec).SetMantExp(ex, -1), nil
+}
+
+// BigTanh returns the hyperbolic tangent of x
+func BigTanh(ctx context.Context, x *big.Float, prec uint) (*big.Float, error) {
+	// tanh x rounds to ±1 once e^-2|x| is below the precision
+	if exponent(x) > 0 && cmpAbs(x, big.NewFloat(float64(prec))) > 0 {
+		return bigInt(int64(x.Sign()), prec), nil
+	}
+
+	wp := prec + guardBits
+	s, err := BigSinh(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	c, err := BigCosh(ctx, x, wp)
+	if err != nil {
+		return nil, err
+	}
+	return newBig(prec).Quo(s, c), nil
+}
+
+// bigExpPair returns e^x and e^-x
+func bigExpPair(ctx context.Context, x *big.Float, prec uint) (*big.Float, *big.Float, error) {
+	ex, err := BigExp(ctx, x, prec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ex, newBig(prec).Quo(bigInt(1, prec), ex), nil
+}
+
+// bigFloor returns the largest integer not greater than x
+func bigFloor(x *big.Float) *big.Float {
+	i, acc := x.Int(nil)
+	if x.Sign() < 0 && acc != big.Exact {
+		i.Sub(i, big.NewInt(1))
+	}
+	return newBig(x.Prec()).SetInt(i)
+}
+
+// bigCeil returns the smallest integer not less than x
+func bigCeil(x *big.Float) *big.Float {
+	i, acc := x.Int(nil)
+	if x.Sign() > 0 && acc != big.Exact {
+		i.Add(i, big.NewInt(1))
+	}
+	return newBig(x.Prec()).SetInt(i)
+}
+
+// bigRound rounds x to the nearest integer, halves away from zero
+func bigRound(x *big.Float) *big.Float {
+	a := newBig(x.Prec()).Abs(x)
+	r := bigFloor(a.Add(a, bigHalf(x.Prec())))
+	if x.Sign() < 0 {
+		r.Neg(r)
+	}
+	return r
+}
@@ -0,0 +1,174 @@
+package calculator
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// WordFunc is the implementation of a function on machine words, such as
+// popcount, whose result depends on the word size of programmer mode
+type WordFunc func(d ProgrammerDomain, args []*big.Int) (*big.Int, error)
+
+func (WordFunc) implementation() {}
+
+// Bitwise is implemented by domains with the bitwise operators & | xor
+// << >> >>> and the complement ~
+type Bitwise interface {
+	Bitwise(op string, x, y Value) (Value, error)
+	Complement(x Value) (Value, error)
+}
+
+// maxShift bounds shifts of integers without a word size, so that
+// 1 << 10^12 fails instead of exhausting memory
+const maxShift = maxRatBits
+
+// word wraps n into the word size in two's complement and returns it as an
+// Integer
+func (d ProgrammerDomain) word(n *big.Int) Value {
+	return NewInteger(d.arith().wrapWord(n))
+}
+
+// modulus returns 2^Bits
+func (d ProgrammerDomain) modulus() *big.Int {
+	return d.arith().modulus()
+}
+
+// BitPattern returns the bits that hold n in a word, read as an unsigned
+// number, so -1 is 0xFF in 8-bit words. Without a word size it returns n.
+func (d ProgrammerDomain) BitPattern(n *big.Int) *big.Int {
+	if d.Bits == 0 {
+		return new(big.Int).Set(n)
+	}
+	return new(big.Int).Mod(n, d.modulus())
+}
+
+func (d ProgrammerDomain) Bitwise(op string, x, y Value) (Value, error) {
+	a, err := d.integer(x)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.integer(y)
+	if err != nil {
+		return nil, err
+	}
+
+	// big.Int gives & | xor and >> the semantics of an infinitely wide
+	// two's complement word, which wrapping then cuts to size
+	z := new(big.Int)
+	switch op {
+	case "&":
+		return d.word(z.And(a, b)), nil
+	case "|":
+		return d.word(z.Or(a, b)), nil
+	case "xor":
+		return d.word(z.Xor(a, b)), nil
+	case "<<", ">>", ">>>":
+		// The count is read as a signed word, so that -1 is an error in
+		// unsigned words too, where it has wrapped to the largest word
+		if d.Unsigned && d.Bits > 0 && b.Bit(d.Bits-1) == 1 {
+			b = new(big.Int).Sub(b, d.modulus())
+		}
+		if b.Sign() < 0 {
+			return nil, fmt.Errorf("negative shift count: %s", b)
+		}
+		if d.Bits > 0 && b.Cmp(big.NewInt(int64(d.Bits))) > 0 {
+			// Every bit has been shifted out
+			b = big.NewInt(int64(d.Bits))
+		}
+		if !b.IsInt64() || b.Int64() > maxShift {
+			return nil, fmt.Errorf("shift count too large: %s", b)
+		}
+		s := uint(b.Int64())
+
+		switch op {
+		case "<<":
+			return d.word(z.Lsh(a, s)), nil
+		case ">>":
+			return d.word(z.Rsh(a, s)), nil
+		default:
+			if a.Sign() < 0 && d.Bits == 0 {
+				return nil, fmt.Errorf("operator >>> needs a word size for negative numbers")
+			}
+			return d.word(z.Rsh(d.BitPattern(a), s)), nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+// Complement flips every bit of x, so ~x is -x-1 in signed words
+func (d ProgrammerDomain) Complement(x Value) (Value, error) {
+	n, err := d.integer(x)
+	if err != nil {
+		return nil, err
+	}
+	return d.word(n.Not(n)), nil
+}
+
+// PopCount returns the number of 1 bits in the word holding x
+func PopCount(d ProgrammerDomain, args []*big.Int) (*big.Int, error) {
+	n := d.BitPattern(args[0])
+	if n.Sign() < 0 {
+		return nil, fmt.Errorf("popcount of a negative number needs a word size")
+	}
+
+	count := 0
+	for _, w := range n.Bits() {
+		for ; w != 0; w &= w - 1 {
+			count++
+		}
+	}
+	return big.NewInt(int64(count)), nil
+}
+
+// LeadingZeros returns the number of 0 bits above the highest 1 bit in the
+// word holding x
+func LeadingZeros(d ProgrammerDomain, args []*big.Int) (*big.Int, error) {
+	if d.Bits == 0 {
+		return nil, fmt.Errorf("clz needs a word size")
+	}
+	return big.NewInt(int64(d.Bits - d.BitPattern(args[0]).BitLen())), nil
+}
+
+// TrailingZeros returns the number of 0 bits below the lowest 1 bit of x,
+// or the word size for 0
+func TrailingZeros(d ProgrammerDomain, args []*big.Int) (*big.Int, error) {
+	n := args[0]
+	if n.Sign() == 0 {
+		if d.Bits == 0 {
+			return nil, fmt.Errorf("ctz(0) needs a word size")
+		}
+		return big.NewInt(int64(d.Bits)), nil
+	}
+	// -x has the same trailing zeros as x in two's complement
+	return big.NewInt(int64(new(big.Int).Abs(n).TrailingZeroBits())), nil
+}
+
+// RotateLeft rotates the word holding x left by y bits; a negative y
+// rotates right
+func RotateLeft(d ProgrammerDomain, args []*big.Int) (*big.Int, error) {
+	if d.Bits == 0 {
+		return nil, fmt.Errorf("rotl needs a word size")
+	}
+
+	bits := big.NewInt(int64(d.Bits))
+	s := uint(new(big.Int).Mod(args[1], bits).Int64())
+	n := d.BitPattern(args[0])
+
+	high := new(big.Int).Lsh(n, s)
+	low := new(big.Int).Rsh(n, uint(d.Bits)-s)
+	return high.Or(high, low), nil
+}
+
+// RotateRight rotates the word holding x right by y bits
+func RotateRight(d ProgrammerDomain, args []*big.Int) (*big.Int, error) {
+	return RotateLeft(d, []*big.Int{args[0], new(big.Int).Neg(args[1])})
+}
+
+// wordOnly is the float64 implementation of a word function, which has no
+// meaning outside programmer mode
+func wordOnly(name string) FloatFunc {
+	return func(args []float64) (float64, error) {
+		return 0, fmt.Errorf("%s needs programmer mode", name)
+	}
+}
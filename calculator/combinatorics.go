@@ -0,0 +1,301 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Bounds on the arguments of the exact combinatorial functions, so that a
+// typo cannot start a computation that runs for minutes
+const (
+	maxCombinatoric = 100000 // nCr, nPr, multinomial, catalan
+	maxStirling     = 1000
+	maxPartitions   = 10000
+)
+
+// checkRange returns the value of n as an int64 if it lies in [lo, hi]
+func checkRange(name string, n *big.Int, lo, hi int64) (int64, error) {
+	if n.Cmp(big.NewInt(lo)) < 0 {
+		return 0, fmt.Errorf("%s expects arguments of at least %d", name, lo)
+	}
+	if n.Cmp(big.NewInt(hi)) > 0 {
+		return 0, fmt.Errorf("%s argument too large (maximum %d)", name, hi)
+	}
+	return n.Int64(), nil
+}
+
+// Binomial returns the binomial coefficient C(n, k), the number of ways to
+// choose k items from n. For negative n it uses C(n, k) = (-1)^k C(k-n-1, k).
+func Binomial(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	if args[1].Sign() < 0 {
+		return big.NewInt(0), nil
+	}
+	n, err := checkRange("nCr", args[0], -maxCombinatoric, maxCombinatoric)
+	if err != nil {
+		return nil, err
+	}
+	k, err := checkRange("nCr", args[1], 0, maxCombinatoric)
+	if err != nil {
+		return nil, err
+	}
+	if n >= 0 && k > n {
+		return big.NewInt(0), nil
+	}
+
+	if n >= 0 {
+		return binomial(ctx, n, k)
+	}
+	result, err := binomial(ctx, k-n-1, k)
+	if err != nil {
+		return nil, err
+	}
+	if k%2 == 1 {
+		result.Neg(result)
+	}
+	return result, nil
+}
+
+// Permutations returns nPr = n!/(n-k)!, the number of ordered selections of
+// k items from n
+func Permutations(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	n, err := checkRange("nPr", args[0], 0, maxCombinatoric)
+	if err != nil {
+		return nil, err
+	}
+	k, err := checkRange("nPr", args[1], 0, maxCombinatoric)
+	if err != nil {
+		return nil, err
+	}
+	if k > n {
+		return big.NewInt(0), nil
+	}
+	return mulRange(ctx, n-k+1, n)
+}
+
+// Multinomial returns (k1 + k2 + ...)! / (k1! k2! ...), the number of ways
+// to split a set into groups of the given sizes
+func Multinomial(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	result := big.NewInt(1)
+	var sum int64
+	for _, arg := range args {
+		k, err := checkRange("multinomial", arg, 0, maxCombinatoric)
+		if err != nil {
+			return nil, err
+		}
+		sum += k
+		if sum > maxCombinatoric {
+			return nil, fmt.Errorf("multinomial argument too large (maximum sum %d)", maxCombinatoric)
+		}
+		// The product of binomials C(k1+...+ki, ki) telescopes to the quotient
+		c, err := binomial(ctx, sum, k)
+		if err != nil {
+			return nil, err
+		}
+		result.Mul(result, c)
+	}
+	return result, nil
+}
+
+// Catalan returns the nth Catalan number C(2n, n)/(n+1)
+func Catalan(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	n, err := checkRange("catalan", args[0], 0, maxCombinatoric/2)
+	if err != nil {
+		return nil, err
+	}
+	result, err := binomial(ctx, 2*n, n)
+	if err != nil {
+		return nil, err
+	}
+	return result.Quo(result, big.NewInt(n+1)), nil
+}
+
+// Stirling1 returns the unsigned Stirling number of the first kind, the
+// number of permutations of n elements with k cycles
+func Stirling1(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	return stirling(ctx, "stirling1", args, func(i, k int64) int64 { return i - 1 })
+}
+
+// Stirling2 returns the Stirling number of the second kind, the number of
+// ways to partition n elements into k non-empty subsets
+func Stirling2(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	return stirling(ctx, "stirling2", args, func(i, k int64) int64 { return k })
+}
+
+// stirling evaluates the recurrence S(i, k) = factor(i, k)·S(i-1, k) +
+// S(i-1, k-1) shared by both kinds, keeping one row at a time
+func stirling(ctx context.Context, name string, args []*big.Int, factor func(i, k int64) int64) (*big.Int, error) {
+	n, err := checkRange(name, args[0], 0, maxStirling)
+	if err != nil {
+		return nil, err
+	}
+	k, err := checkRange(name, args[1], 0, maxStirling)
+	if err != nil {
+		return nil, err
+	}
+	if k > n {
+		return big.NewInt(0), nil
+	}
+
+	row := make([]*big.Int, k+1)
+	for j := range row {
+		row[j] = new(big.Int)
+	}
+	row[0].SetInt64(1)
+
+	tmp := new(big.Int)
+	for i := int64(1); i <= n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Update in place from the right so that row[j-1] is still S(i-1, j-1)
+		for j := min(i, k); j >= 1; j-- {
+			tmp.Mul(row[j], big.NewInt(factor(i, j)))
+			row[j].Add(tmp, row[j-1])
+		}
+		row[0].SetInt64(0)
+	}
+	return row[k], nil
+}
+
+// Partitions returns p(n), the number of ways to write n as a sum of
+// positive integers, using Euler's pentagonal number recurrence
+func Partitions(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	if args[0].Sign() < 0 {
+		return big.NewInt(0), nil
+	}
+	n, err := checkRange("partitions", args[0], 0, maxPartitions)
+	if err != nil {
+		return nil, err
+	}
+
+	p := make([]*big.Int, n+1)
+	p[0] = big.NewInt(1)
+	for m := int64(1); m <= n; m++ {
+		if m%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		sum := new(big.Int)
+		for k := int64(1); ; k++ {
+			g1 := k * (3*k - 1) / 2
+			if g1 > m {
+				break
+			}
+			term := new(big.Int).Set(p[m-g1])
+			if g2 := k * (3*k + 1) / 2; g2 <= m {
+				term.Add(term, p[m-g2])
+			}
+			if k%2 == 1 {
+				sum.Add(sum, term)
+			} else {
+				sum.Sub(sum, term)
+			}
+		}
+		p[m] = sum
+	}
+	return p[n], nil
+}
+
+// binomial returns C(n, k) for 0 <= k <= n, like big.Int.Binomial
+func binomial(ctx context.Context, n, k int64) (*big.Int, error) {
+	k = min(k, n-k)
+	num, err := mulRange(ctx, n-k+1, n)
+	if err != nil {
+		return nil, err
+	}
+	den, err := mulRange(ctx, 1, k)
+	if err != nil {
+		return nil, err
+	}
+	return num.Quo(num, den), nil
+}
+
+// mulRange returns the product of the integers in [a, b], like
+// big.Int.MulRange, checking the context between blocks of factors
+func mulRange(ctx context.Context, a, b int64) (*big.Int, error) {
+	const block = 1024
+	result := big.NewInt(1)
+	for lo := a; lo <= b; lo += block {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.Mul(result, new(big.Int).MulRange(lo, min(lo+block-1, b)))
+	}
+	return result, nil
+}
+
+// GeneralBinomial returns C(x, y) = Γ(x+1) / (Γ(y+1) Γ(x-y+1)) for real x
+// and y
+func GeneralBinomial(x, y float64) (float64, error) {
+	return gammaRatio("nCr", x+1, y+1, x-y+1)
+}
+
+// GeneralPermutations returns Γ(x+1) / Γ(x-y+1) for real x and y
+func GeneralPermutations(x, y float64) (float64, error) {
+	return gammaRatio("nPr", x+1, x-y+1)
+}
+
+// gammaRatio returns Γ(num) / (Γ(den[0]) Γ(den[1]) ...). A pole in the
+// denominator makes the ratio zero.
+func gammaRatio(name string, num float64, den ...float64) (float64, error) {
+	isPole := func(x float64) bool { return x <= 0 && x == math.Trunc(x) }
+
+	if isPole(num) {
+		return 0, fmt.Errorf("%s undefined at a pole of gamma", name)
+	}
+
+	// Gamma itself is more accurate than Lgamma while nothing overflows
+	direct := num > 0 && num < 170
+	for _, d := range den {
+		direct = direct && d > 0 && d < 170
+	}
+	if direct {
+		result := math.Gamma(num)
+		for _, d := range den {
+			result /= math.Gamma(d)
+		}
+		return result, nil
+	}
+
+	lg, sign := math.Lgamma(num)
+	for _, d := range den {
+		if isPole(d) {
+			return 0, nil
+		}
+		l, s := math.Lgamma(d)
+		lg -= l
+		sign *= s
+	}
+	return float64(sign) * math.Exp(lg), nil
+}
+
+// intFloat builds the float64 implementation of an integer function: integer
+// arguments are computed exactly and rounded, others go to fallback, which
+// may be nil if the function is only defined on integers
+func intFloat(name string, fn IntFunc, fallback FloatFunc) FloatFunc {
+	return func(args []float64) (float64, error) {
+		ints := make([]*big.Int, len(args))
+		for i, x := range args {
+			n, ok := floatInteger(x)
+			if !ok {
+				if err := inexactError(name, x); err != nil {
+					return 0, err
+				}
+				if fallback == nil {
+					return 0, fmt.Errorf("%s expects integer arguments", name)
+				}
+				return fallback(args)
+			}
+			ints[i] = n
+		}
+
+		n, err := fn(context.Background(), ints)
+		if err != nil {
+			return 0, err
+		}
+		return NewInteger(n).Float64(), nil
+	}
+}
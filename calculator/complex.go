@@ -0,0 +1,393 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strconv"
+)
+
+// ComplexFunc is the complex implementation of a function
+type ComplexFunc func(args []complex128) (complex128, error)
+
+func (ComplexFunc) implementation() {}
+
+// Complex is a complex128 value produced by ComplexDomain
+type Complex complex128
+
+// Float64 returns the real part. Callers that need the imaginary part must
+// check for a Complex value.
+func (z Complex) Float64() float64 {
+	return real(z)
+}
+
+// IsReal reports whether the imaginary part is zero
+func (z Complex) IsReal() bool {
+	return imag(z) == 0
+}
+
+// String renders z in rectangular form with every digit, e.g. 3+4i, -2i or
+// 1.5 when the imaginary part is zero. ParseComplexNumber accepts the result.
+func (z Complex) String() string {
+	re, im := real(z), imag(z)
+	if im == 0 {
+		return strconv.FormatFloat(re, 'g', -1, 64)
+	}
+
+	imText := strconv.FormatFloat(im, 'g', -1, 64) + "i"
+	if re == 0 {
+		return imText
+	}
+	if im > 0 || math.IsNaN(im) {
+		imText = "+" + imText
+	}
+	return strconv.FormatFloat(re, 'g', -1, 64) + imText
+}
+
+// ComplexDomain evaluates with complex128 arithmetic. The constant i is the
+// imaginary unit, so sqrt(-4) = 2i and log(-1) = πi. Functions registered
+// without a ComplexFunc accept real arguments only.
+type ComplexDomain struct{}
+
+func (ComplexDomain) Name() string {
+	return "complex"
+}
+
+// value wraps a result. Like FloatDomain, it lets NaN and infinities
+// through for the evaluator to judge.
+func (ComplexDomain) value(z complex128) (Value, error) {
+	return Complex(positiveZero(z)), nil
+}
+
+// positiveZero clears the sign of a zero imaginary part. Otherwise -4 would
+// be -4-0i, which lies below the branch cut of sqrt and log, and sqrt(-4)
+// would give -2i.
+func positiveZero(z complex128) complex128 {
+	if imag(z) == 0 {
+		return complex(real(z), 0)
+	}
+	return z
+}
+
+// complex converts any value to complex128
+func (ComplexDomain) complex(x Value) complex128 {
+	if z, ok := x.(Complex); ok {
+		return complex128(z)
+	}
+	return complex(x.Float64(), 0)
+}
+
+func (d ComplexDomain) Literal(text string) (Value, error) {
+	x, err := FloatDomain{}.Literal(text)
+	if err != nil {
+		return nil, err
+	}
+	return d.Convert(x)
+}
+
+func (ComplexDomain) Constant(name string) (Value, bool) {
+	switch name {
+	case "i":
+		return Complex(1i), true
+	case "pi", "π":
+		return Complex(complex(Pi, 0)), true
+	case "e":
+		return Complex(complex(E, 0)), true
+	}
+	return nil, false
+}
+
+func (d ComplexDomain) Convert(x Value) (Value, error) {
+	return Complex(d.complex(x)), nil
+}
+
+func (ComplexDomain) Special(f float64) Value {
+	return Complex(complex(f, 0))
+}
+
+func (ComplexDomain) Bool(b bool) Value {
+	return Complex(complex(FromBool(b), 0))
+}
+
+func (d ComplexDomain) Truthy(x Value) bool {
+	return d.complex(x) != 0
+}
+
+func (d ComplexDomain) Negate(x Value) (Value, error) {
+	return d.value(-d.complex(x))
+}
+
+func (d ComplexDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	a, b := d.complex(x), d.complex(y)
+
+	switch op {
+	case "+":
+		return d.value(a + b)
+	case "-":
+		return d.value(a - b)
+	case "*":
+		return d.value(a * b)
+	case "/":
+		if b == 0 {
+			// The one point at infinity, or NaN for 0/0
+			inf := math.Inf(1)
+			if a == 0 {
+				inf = math.NaN()
+			} else if imag(a) == 0 && real(a) < 0 {
+				inf = math.Inf(-1)
+			}
+			return nil, domainError(inf, "division by zero")
+		}
+		return d.value(a / b)
+	case "%":
+		if imag(a) != 0 || imag(b) != 0 {
+			return nil, fmt.Errorf("modulus undefined for complex numbers")
+		}
+		result, err := Modulus(real(a), real(b))
+		if err != nil {
+			return nil, err
+		}
+		return d.value(complex(result, 0))
+	case "^":
+		result, err := ComplexPow(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return d.value(result)
+	case "==", "!=":
+		return d.Bool((a == b) == (op == "==")), nil
+	case "<", "<=", ">", ">=":
+		if imag(a) != 0 || imag(b) != 0 {
+			return nil, fmt.Errorf("complex numbers cannot be ordered with %s", op)
+		}
+		result, err := Compare(op, real(a), real(b))
+		if err != nil {
+			return nil, err
+		}
+		return d.Bool(Truthy(result)), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+func (d ComplexDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	z := d.complex(x)
+	if imag(z) != 0 {
+		result, err := ComplexGamma(z + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.value(result)
+	}
+	result, err := Factorial(real(z))
+	if err != nil {
+		return nil, err
+	}
+	return d.value(complex(result, 0))
+}
+
+func (d ComplexDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	z := d.complex(x)
+	if imag(z) != 0 {
+		return nil, fmt.Errorf("double factorial undefined for complex numbers")
+	}
+	result, err := DoubleFactorial(real(z))
+	if err != nil {
+		return nil, err
+	}
+	return d.value(complex(result, 0))
+}
+
+// Call returns the exact result of an integer function as an Integer, like
+// FloatDomain
+func (d ComplexDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		return v, err
+	}
+
+	zs := make([]complex128, len(args))
+	for i, arg := range args {
+		zs[i] = d.complex(arg)
+	}
+
+	if impl, ok := ImplementationOf[ComplexFunc](f); ok {
+		result, err := impl(zs)
+		if err != nil {
+			return nil, err
+		}
+		return d.value(result)
+	}
+
+	for _, z := range zs {
+		if imag(z) != 0 {
+			return nil, fmt.Errorf("%s is not defined for complex arguments", f.Name)
+		}
+	}
+	result, err := FloatDomain{}.Call(ctx, f, args)
+	if err != nil {
+		return nil, err
+	}
+	return d.Convert(result)
+}
+
+// ComplexPow returns the principal value of x^y. Integer exponents are
+// computed by repeated multiplication so that i^2 is exactly -1, and real
+// powers with a real result use math.Pow.
+func ComplexPow(x, y complex128) (complex128, error) {
+	if imag(y) == 0 {
+		n := real(y)
+		if imag(x) == 0 && (real(x) >= 0 || n == math.Trunc(n)) {
+			return complex(math.Pow(real(x), n), 0), nil
+		}
+		if n == math.Trunc(n) && math.Abs(n) <= 1<<16 {
+			if x == 0 && n < 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			result := complexPowInt(x, int64(math.Abs(n)))
+			if n < 0 {
+				result = 1 / result
+			}
+			return result, nil
+		}
+	}
+	if x == 0 {
+		if real(y) > 0 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("0 raised to a complex power with non-positive real part")
+	}
+	return cmplx.Pow(x, y), nil
+}
+
+// complexPowInt computes x^n by binary exponentiation
+func complexPowInt(x complex128, n int64) complex128 {
+	result := complex128(1)
+	for n > 0 {
+		if n&1 == 1 {
+			result *= x
+		}
+		x *= x
+		n >>= 1
+	}
+	return result
+}
+
+// ComplexCbrt returns the real cube root of a real number and the principal
+// cube root otherwise
+func ComplexCbrt(z complex128) complex128 {
+	if imag(z) == 0 {
+		return complex(math.Cbrt(real(z)), 0)
+	}
+	return cmplx.Pow(z, 1.0/3)
+}
+
+// ComplexArg returns the argument (phase) of z in (-π, π]
+func ComplexArg(z complex128) complex128 {
+	return complex(cmplx.Phase(z), 0)
+}
+
+// complexUnary adapts a single-argument complex function to a ComplexFunc
+func complexUnary(fn func(complex128) complex128) ComplexFunc {
+	return func(args []complex128) (complex128, error) {
+		return fn(args[0]), nil
+	}
+}
+
+// complexUnaryErr adapts a single-argument complex function that can fail
+// to a ComplexFunc
+func complexUnaryErr(fn func(complex128) (complex128, error)) ComplexFunc {
+	return func(args []complex128) (complex128, error) {
+		return fn(args[0])
+	}
+}
+
+// complexLog wraps a logarithm, which is undefined at zero
+func complexLog(fn func(complex128) complex128) ComplexFunc {
+	return func(args []complex128) (complex128, error) {
+		if args[0] == 0 {
+			return 0, domainError(math.Inf(-1), "logarithm undefined for zero")
+		}
+		return fn(args[0]), nil
+	}
+}
+
+// complexRounding applies a rounding function to the real and imaginary
+// parts separately
+func complexRounding(fn FloatFunc) ComplexFunc {
+	return func(args []complex128) (complex128, error) {
+		rest := make([]float64, len(args)-1)
+		for i, arg := range args[1:] {
+			if imag(arg) != 0 {
+				return 0, fmt.Errorf("number of decimal places must be real")
+			}
+			rest[i] = real(arg)
+		}
+
+		re, err := fn(append([]float64{real(args[0])}, rest...))
+		if err != nil {
+			return 0, err
+		}
+		im, err := fn(append([]float64{imag(args[0])}, rest...))
+		if err != nil {
+			return 0, err
+		}
+		return complex(re, im), nil
+	}
+}
+
+// ComplexRe returns the real part of z
+func ComplexRe(z complex128) complex128 {
+	return complex(real(z), 0)
+}
+
+// ComplexIm returns the imaginary part of z
+func ComplexIm(z complex128) complex128 {
+	return complex(imag(z), 0)
+}
+
+// ComplexAbs returns the modulus of z
+func ComplexAbs(z complex128) complex128 {
+	return complex(cmplx.Abs(z), 0)
+}
+
+// Re, Im and Arg are the parts of a real number, used outside complex mode
+func Re(x float64) float64 {
+	return x
+}
+
+func Im(x float64) float64 {
+	return 0
+}
+
+func Arg(x float64) float64 {
+	if x < 0 {
+		return Pi
+	}
+	return 0
+}
+
+// BigArg is Arg for arbitrary-precision values
+func BigArg(x *big.Float, prec uint) *big.Float {
+	if x.Sign() < 0 {
+		return BigPi(prec)
+	}
+	return newBig(prec)
+}
+
+func bigCopy(x *big.Float, prec uint) *big.Float {
+	return newBig(prec).Set(x)
+}
+
+func bigZero(x *big.Float, prec uint) *big.Float {
+	return newBig(prec)
+}
+
+func ratCopy(x *big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Set(x), nil
+}
+
+func ratZero(x *big.Rat) (*big.Rat, error) {
+	return new(big.Rat), nil
+}
@@ -0,0 +1,426 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FloatFunc is the float64 implementation of a function. The registry checks
+// the argument count before calling it.
+type FloatFunc func(args []float64) (float64, error)
+
+// Implementation is an implementation of a function for one kind of
+// number, such as a BigFunc for arbitrary precision or a RatFunc for exact
+// rationals. Each domain looks for the kinds it can use with
+// ImplementationOf and falls back to the float64 Impl.
+type Implementation interface {
+	implementation()
+}
+
+// Function describes a function that can be called from expressions. Impl
+// is required. Modes holds optional implementations for the other domains,
+// at most one of each kind: a BigFunc, RatFunc, ComplexFunc or IntervalFunc
+// for arbitrary-precision, rational, complex and interval mode, a DerivFunc
+// with the partial derivatives used in uncertainty mode, a FigureRule for
+// sig-fig mode and a WordFunc for programmer mode. An IntFunc or
+// IntValueFunc is preferred in every mode when all arguments are integers,
+// and a ValueFunc replaces all of them in every mode.
+type Function struct {
+	Name     string
+	MinArgs  int
+	MaxArgs  int // -1 for no upper limit
+	Impl     FloatFunc
+	Modes    []Implementation
+	Doc      string
+	AngleIn  bool // arguments are angles (converted from degrees in deg mode)
+	AngleOut bool // result is an angle (converted to degrees in deg mode)
+}
+
+// ImplementationOf returns the implementation of f of kind T, if it has one
+func ImplementationOf[T Implementation](f Function) (T, bool) {
+	for _, impl := range f.Modes {
+		if t, ok := impl.(T); ok {
+			return t, true
+		}
+	}
+	var none T
+	return none, false
+}
+
+// Signature renders the call form of the function, e.g. round(x[, y])
+func (f Function) Signature() string {
+	params := []string{"x", "y", "z"}
+	var sb strings.Builder
+
+	sb.WriteString(f.Name + "(")
+	for i := 0; i < f.MinArgs; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(params[i%len(params)])
+	}
+
+	switch {
+	case f.MaxArgs < 0:
+		sb.WriteString(", ...")
+	case f.MaxArgs > f.MinArgs:
+		for i := f.MinArgs; i < f.MaxArgs; i++ {
+			sb.WriteString("[, " + params[i%len(params)] + "]")
+		}
+	}
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+// CheckArgs verifies that n arguments are acceptable for the function
+func (f Function) CheckArgs(n int) error {
+	if n >= f.MinArgs && (f.MaxArgs < 0 || n <= f.MaxArgs) {
+		return nil
+	}
+
+	switch {
+	case f.MaxArgs < 0:
+		return fmt.Errorf("%s expects at least %d argument(s)", f.Name, f.MinArgs)
+	case f.MinArgs == f.MaxArgs:
+		return fmt.Errorf("%s expects %d argument(s)", f.Name, f.MinArgs)
+	default:
+		return fmt.Errorf("%s expects %d to %d arguments", f.Name, f.MinArgs, f.MaxArgs)
+	}
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Function)
+
+	functionNameRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	identifierRegex   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// RegisterFunction adds a function to the registry shared by the evaluator,
+// the reserved-name check and the help screen. Registering an existing name replaces it.
+func RegisterFunction(name string, minArgs, maxArgs int, impl FloatFunc, doc string) error {
+	return Register(Function{
+		Name:    name,
+		MinArgs: minArgs,
+		MaxArgs: maxArgs,
+		Impl:    impl,
+		Doc:     doc,
+	})
+}
+
+// Register adds a fully described function to the registry
+func Register(f Function) error {
+	if !functionNameRegex.MatchString(f.Name) {
+		return fmt.Errorf("invalid function name: %s", f.Name)
+	}
+	if f.Impl == nil {
+		return fmt.Errorf("function %s has no implementation", f.Name)
+	}
+	if f.MinArgs < 0 || (f.MaxArgs >= 0 && f.MaxArgs < f.MinArgs) {
+		return fmt.Errorf("invalid argument range for %s", f.Name)
+	}
+	kinds := make(map[string]bool, len(f.Modes))
+	for _, impl := range f.Modes {
+		if impl == nil {
+			return fmt.Errorf("function %s has a nil implementation", f.Name)
+		}
+		kind := fmt.Sprintf("%T", impl)
+		if kinds[kind] {
+			return fmt.Errorf("function %s has more than one %s implementation", f.Name, kind)
+		}
+		kinds[kind] = true
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[f.Name] = f
+	return nil
+}
+
+// LookupFunction returns the registered function with the given name
+func LookupFunction(name string) (Function, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[strings.ToLower(name)]
+	return f, ok
+}
+
+// IsFunction reports whether name is a registered function
+func IsFunction(name string) bool {
+	_, ok := LookupFunction(name)
+	return ok
+}
+
+// IsReservedKeyword reports whether s is a constant, a keyword, a REPL
+// command or a registered function
+func IsReservedKeyword(s string) bool {
+	reserved := map[string]bool{
+		"pi": true, "e": true, "ans": true,
+		"not": true, "xor": true,
+		"exit": true, "quit": true,
+		"help": true, "clear": true,
+		"mem": true, "history": true,
+	}
+
+	return reserved[strings.ToLower(s)] || IsFunction(s)
+}
+
+// IsValidVariableName reports whether name can be assigned to
+func IsValidVariableName(name string) bool {
+	return identifierRegex.MatchString(name) && !IsReservedKeyword(name)
+}
+
+// Functions returns all registered functions sorted by name
+func Functions() []Function {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	funcs := make([]Function, 0, len(registry))
+	for _, f := range registry {
+		funcs = append(funcs, f)
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		return funcs[i].Name < funcs[j].Name
+	})
+	return funcs
+}
+
+// unary adapts a single-argument function to a FloatFunc
+func unary(fn func(float64) float64) FloatFunc {
+	return func(args []float64) (float64, error) {
+		return fn(args[0]), nil
+	}
+}
+
+// unaryErr adapts a single-argument function that can fail to a FloatFunc
+func unaryErr(fn func(float64) (float64, error)) FloatFunc {
+	return func(args []float64) (float64, error) {
+		return fn(args[0])
+	}
+}
+
+// rounding applies fn at an optional number of decimal places
+func rounding(fn func(float64) float64) FloatFunc {
+	return func(args []float64) (float64, error) {
+		if len(args) == 1 {
+			return fn(args[0]), nil
+		}
+		multiplier := math.Pow(10, math.Trunc(args[1]))
+		return fn(args[0]*multiplier) / multiplier, nil
+	}
+}
+
+// bigUnary adapts a single-argument arbitrary-precision function to a BigFunc
+func bigUnary(fn func(*big.Float, uint) *big.Float) BigFunc {
+	return func(ctx context.Context, args []*big.Float, prec uint) (*big.Float, error) {
+		return fn(args[0], prec), nil
+	}
+}
+
+// bigUnaryErr adapts a single-argument arbitrary-precision function that can
+// fail to a BigFunc
+func bigUnaryErr(fn func(*big.Float, uint) (*big.Float, error)) BigFunc {
+	return func(ctx context.Context, args []*big.Float, prec uint) (*big.Float, error) {
+		return fn(args[0], prec)
+	}
+}
+
+// bigSeries adapts a single-argument arbitrary-precision function that
+// sums a series, and so can be cancelled, to a BigFunc
+func bigSeries(fn func(context.Context, *big.Float, uint) (*big.Float, error)) BigFunc {
+	return func(ctx context.Context, args []*big.Float, prec uint) (*big.Float, error) {
+		return fn(ctx, args[0], prec)
+	}
+}
+
+// bigRounding is rounding for arbitrary-precision values
+func bigRounding(fn func(*big.Float) *big.Float) BigFunc {
+	return func(ctx context.Context, args []*big.Float, prec uint) (*big.Float, error) {
+		if len(args) == 1 {
+			return fn(args[0]), nil
+		}
+		places, _ := args[1].Int64()
+		multiplier, err := bigPowInt(bigInt(10, prec), places, prec)
+		if err != nil {
+			return nil, err
+		}
+		x := newBig(prec).Mul(args[0], multiplier)
+		return x.Quo(fn(x), multiplier), nil
+	}
+}
+
+// bigExtreme returns the smallest argument when want is -1 and the largest
+// when want is 1
+func bigExtreme(want int) BigFunc {
+	return func(ctx context.Context, args []*big.Float, prec uint) (*big.Float, error) {
+		best := args[0]
+		for _, x := range args[1:] {
+			if x.Cmp(best) == want {
+				best = x
+			}
+		}
+		return newBig(prec).Set(best), nil
+	}
+}
+
+// ratRounding applies a rounding function exactly at an optional number of
+// decimal places
+func ratRounding(fn func(*big.Rat) *big.Int) RatFunc {
+	return func(args []*big.Rat) (*big.Rat, error) {
+		if len(args) == 1 {
+			return new(big.Rat).SetInt(fn(args[0])), nil
+		}
+
+		places := new(big.Int).Quo(args[1].Num(), args[1].Denom())
+		if !places.IsInt64() || places.Int64() > 4096 || places.Int64() < -4096 {
+			return nil, fmt.Errorf("too many decimal places")
+		}
+		scale, _ := RatPow(big.NewRat(10, 1), new(big.Rat).SetInt(places))
+		x := new(big.Rat).Mul(args[0], scale)
+		x.SetInt(fn(x))
+		return x.Quo(x, scale), nil
+	}
+}
+
+// ratExtreme returns the smallest argument when want is -1 and the largest
+// when want is 1
+func ratExtreme(want int) RatFunc {
+	return func(args []*big.Rat) (*big.Rat, error) {
+		best := args[0]
+		for _, x := range args[1:] {
+			if x.Cmp(best) == want {
+				best = x
+			}
+		}
+		return new(big.Rat).Set(best), nil
+	}
+}
+
+// ratUnary adapts a single-argument rational function to a RatFunc
+func ratUnary(fn func(*big.Rat) (*big.Rat, error)) RatFunc {
+	return func(args []*big.Rat) (*big.Rat, error) {
+		return fn(args[0])
+	}
+}
+
+func mustRegister(f Function) {
+	if err := Register(f); err != nil {
+		panic(err)
+	}
+}
+
+func init() {
+	builtins := []Function{
+		// Trigonometric
+		{Name: "sin", MinArgs: 1, MaxArgs: 1, Impl: unary(Sin), Modes: []Implementation{bigSeries(BigSin), complexUnary(cmplx.Sin), periodic(Sin, Pi/2), derivUnary(math.Cos)}, Doc: "Sine", AngleIn: true},
+		{Name: "cos", MinArgs: 1, MaxArgs: 1, Impl: unary(Cos), Modes: []Implementation{bigSeries(BigCos), complexUnary(cmplx.Cos), periodic(Cos, 0), derivUnary(func(x float64) float64 { return -math.Sin(x) })}, Doc: "Cosine", AngleIn: true},
+		{Name: "tan", MinArgs: 1, MaxArgs: 1, Impl: unary(Tan), Modes: []Implementation{bigSeries(BigTan), complexUnary(cmplx.Tan), IntervalFunc(IntervalTan), derivUnary(func(x float64) float64 { return 1 + math.Tan(x)*math.Tan(x) })}, Doc: "Tangent", AngleIn: true},
+		{Name: "asin", MinArgs: 1, MaxArgs: 1, Impl: unaryErr(Asin), Modes: []Implementation{bigSeries(BigAsin), complexUnary(cmplx.Asin), monotone(Asin, true), derivUnary(func(x float64) float64 { return 1 / math.Sqrt(1-x*x) })}, Doc: "Inverse sine", AngleOut: true},
+		{Name: "acos", MinArgs: 1, MaxArgs: 1, Impl: unaryErr(Acos), Modes: []Implementation{bigSeries(BigAcos), complexUnary(cmplx.Acos), monotone(Acos, false), derivUnary(func(x float64) float64 { return -1 / math.Sqrt(1-x*x) })}, Doc: "Inverse cosine", AngleOut: true},
+		{Name: "atan", MinArgs: 1, MaxArgs: 1, Impl: unary(Atan), Modes: []Implementation{bigSeries(BigAtan), complexUnary(cmplx.Atan), monotone(noErr(Atan), true), derivUnary(func(x float64) float64 { return 1 / (1 + x*x) })}, Doc: "Inverse tangent", AngleOut: true},
+
+		// Hyperbolic
+		{Name: "sinh", MinArgs: 1, MaxArgs: 1, Impl: unary(Sinh), Modes: []Implementation{bigSeries(BigSinh), complexUnary(cmplx.Sinh), monotone(noErr(Sinh), true), derivUnary(math.Cosh)}, Doc: "Hyperbolic sine"},
+		{Name: "cosh", MinArgs: 1, MaxArgs: 1, Impl: unary(Cosh), Modes: []Implementation{bigSeries(BigCosh), complexUnary(cmplx.Cosh), valley(noErr(Cosh), 0), derivUnary(math.Sinh)}, Doc: "Hyperbolic cosine"},
+		{Name: "tanh", MinArgs: 1, MaxArgs: 1, Impl: unary(Tanh), Modes: []Implementation{bigSeries(BigTanh), complexUnary(cmplx.Tanh), monotone(noErr(Tanh), true), derivUnary(func(x float64) float64 { return 1 - math.Tanh(x)*math.Tanh(x) })}, Doc: "Hyperbolic tangent"},
+
+		// Roots, logarithms and exponentials
+		{Name: "sqrt", MinArgs: 1, MaxArgs: 1, Impl: unaryErr(Sqrt), Modes: []Implementation{bigUnaryErr(BigSqrt), ratUnary(RatSqrt), complexUnary(cmplx.Sqrt), IntervalFunc(IntervalSqrt), derivUnary(func(x float64) float64 { return 0.5 / math.Sqrt(x) })}, Doc: "Square root"},
+		{Name: "cbrt", MinArgs: 1, MaxArgs: 1, Impl: unary(Cbrt), Modes: []Implementation{bigSeries(BigCbrt), ratUnary(RatCbrt), complexUnary(ComplexCbrt), monotone(noErr(Cbrt), true), derivUnary(func(x float64) float64 { return 1 / (3 * math.Cbrt(x) * math.Cbrt(x)) })}, Doc: "Cube root"},
+		{Name: "log", MinArgs: 1, MaxArgs: 1, Impl: unaryErr(Log), Modes: []Implementation{bigSeries(BigLog), complexLog(cmplx.Log), monotone(Log, true), derivUnary(func(x float64) float64 { return 1 / x }), FiguresLog}, Doc: "Natural logarithm"},
+		{Name: "log10", MinArgs: 1, MaxArgs: 1, Impl: unaryErr(Log10), Modes: []Implementation{bigSeries(BigLog10), complexLog(cmplx.Log10), monotone(Log10, true), derivUnary(func(x float64) float64 { return 1 / (x * math.Ln10) }), FiguresLog}, Doc: "Base-10 logarithm"},
+		{Name: "exp", MinArgs: 1, MaxArgs: 1, Impl: unary(Exp), Modes: []Implementation{bigSeries(BigExp), complexUnary(cmplx.Exp), monotone(noErr(Exp), true), derivUnary(math.Exp), FiguresAntilog}, Doc: "Exponential e^x"},
+		{Name: "pow", MinArgs: 2, MaxArgs: 2, Impl: func(args []float64) (float64, error) {
+			return Power(args[0], args[1]), nil
+		}, Modes: []Implementation{BigFunc(func(ctx context.Context, args []*big.Float, prec uint) (*big.Float, error) {
+			return BigPow(ctx, args[0], args[1], prec)
+		}), RatFunc(func(args []*big.Rat) (*big.Rat, error) {
+			return RatPow(args[0], args[1])
+		}), ComplexFunc(func(args []complex128) (complex128, error) {
+			return ComplexPow(args[0], args[1])
+		}), IntervalFunc(func(args []Interval) (Interval, error) {
+			return IntervalPow(args[0], args[1])
+		}), DerivFunc(derivPow), FiguresFromFirst}, Doc: "Power x^y"},
+
+		// Gamma and beta
+		{Name: "gamma", MinArgs: 1, MaxArgs: 1, Impl: unaryErr(Gamma), Modes: []Implementation{bigUnaryErr(BigGamma), ratUnary(RatGamma), complexUnaryErr(ComplexGamma), IntervalFunc(IntervalGamma), derivUnaryErr(derivGamma)}, Doc: "Gamma function, gamma(n) = (n-1)!"},
+		{Name: "lgamma", MinArgs: 1, MaxArgs: 1, Impl: unaryErr(Lgamma), Modes: []Implementation{IntervalFunc(IntervalLgamma), derivUnaryErr(Digamma)}, Doc: "Natural logarithm of |gamma(x)|"},
+		{Name: "beta", MinArgs: 2, MaxArgs: 2, Impl: func(args []float64) (float64, error) {
+			return Beta(args[0], args[1])
+		}, Modes: []Implementation{BigFunc(BigBeta), RatFunc(RatBeta), ComplexFunc(ComplexBeta), IntervalFunc(IntervalBeta), DerivFunc(derivBeta)}, Doc: "Beta function gamma(x)gamma(y)/gamma(x+y)"},
+
+		// Combinatorics
+		{Name: "ncr", MinArgs: 2, MaxArgs: 2, Impl: intFloat("nCr", Binomial, func(args []float64) (float64, error) {
+			return GeneralBinomial(args[0], args[1])
+		}), Modes: []Implementation{IntFunc(Binomial)}, Doc: "Binomial coefficient, ways to choose y of x"},
+		{Name: "npr", MinArgs: 2, MaxArgs: 2, Impl: intFloat("nPr", Permutations, func(args []float64) (float64, error) {
+			return GeneralPermutations(args[0], args[1])
+		}), Modes: []Implementation{IntFunc(Permutations)}, Doc: "Permutations, ordered choices of y of x"},
+		{Name: "multinomial", MinArgs: 1, MaxArgs: -1, Impl: intFloat("multinomial", Multinomial, nil), Modes: []Implementation{IntFunc(Multinomial)}, Doc: "Multinomial coefficient (x+y+...)!/(x!y!...)"},
+		{Name: "stirling1", MinArgs: 2, MaxArgs: 2, Impl: intFloat("stirling1", Stirling1, nil), Modes: []Implementation{IntFunc(Stirling1)}, Doc: "Permutations of x elements with y cycles (Stirling, 1st kind)"},
+		{Name: "stirling2", MinArgs: 2, MaxArgs: 2, Impl: intFloat("stirling2", Stirling2, nil), Modes: []Implementation{IntFunc(Stirling2)}, Doc: "Partitions of x elements into y subsets (Stirling, 2nd kind)"},
+		{Name: "catalan", MinArgs: 1, MaxArgs: 1, Impl: intFloat("catalan", Catalan, nil), Modes: []Implementation{IntFunc(Catalan)}, Doc: "Catalan number C(2x, x)/(x+1)"},
+		{Name: "partitions", MinArgs: 1, MaxArgs: 1, Impl: intFloat("partitions", Partitions, nil), Modes: []Implementation{IntFunc(Partitions)}, Doc: "Number of integer partitions of x"},
+
+		// Number theory
+		{Name: "isprime", MinArgs: 1, MaxArgs: 1, Impl: intFloat("isprime", IsPrimeInt, nil), Modes: []Implementation{IntFunc(IsPrimeInt)}, Doc: "1 if x is prime, 0 otherwise"},
+		{Name: "nextprime", MinArgs: 1, MaxArgs: 1, Impl: intFloat("nextprime", NextPrime, nil), Modes: []Implementation{IntFunc(NextPrime)}, Doc: "Smallest prime greater than x"},
+		{Name: "factor", MinArgs: 1, MaxArgs: 1, Impl: intValueFloat("factor", Factor), Modes: []Implementation{IntValueFunc(Factor)}, Doc: "Prime factorisation of x"},
+		{Name: "divisors", MinArgs: 1, MaxArgs: 1, Impl: intValueFloat("divisors", Divisors), Modes: []Implementation{IntValueFunc(Divisors)}, Doc: "Positive divisors of x"},
+		{Name: "gcd", MinArgs: 1, MaxArgs: -1, Impl: intFloat("gcd", GCD, nil), Modes: []Implementation{IntFunc(GCD)}, Doc: "Greatest common divisor"},
+		{Name: "lcm", MinArgs: 1, MaxArgs: -1, Impl: intFloat("lcm", LCM, nil), Modes: []Implementation{IntFunc(LCM)}, Doc: "Least common multiple"},
+		{Name: "modpow", MinArgs: 3, MaxArgs: 3, Impl: intFloat("modpow", ModPow, nil), Modes: []Implementation{IntFunc(ModPow)}, Doc: "x^y mod z"},
+		{Name: "modinv", MinArgs: 2, MaxArgs: 2, Impl: intFloat("modinv", ModInverse, nil), Modes: []Implementation{IntFunc(ModInverse)}, Doc: "Inverse of x modulo y"},
+		{Name: "totient", MinArgs: 1, MaxArgs: 1, Impl: intFloat("totient", Totient, nil), Modes: []Implementation{IntFunc(Totient)}, Doc: "Euler's totient, integers up to x coprime to x"},
+
+		// Bit manipulation
+		{Name: "popcount", MinArgs: 1, MaxArgs: 1, Impl: wordOnly("popcount"), Modes: []Implementation{WordFunc(PopCount)}, Doc: "Number of 1 bits in x"},
+		{Name: "clz", MinArgs: 1, MaxArgs: 1, Impl: wordOnly("clz"), Modes: []Implementation{WordFunc(LeadingZeros)}, Doc: "Leading zero bits of x in a word"},
+		{Name: "ctz", MinArgs: 1, MaxArgs: 1, Impl: wordOnly("ctz"), Modes: []Implementation{WordFunc(TrailingZeros)}, Doc: "Trailing zero bits of x"},
+		{Name: "rotl", MinArgs: 2, MaxArgs: 2, Impl: wordOnly("rotl"), Modes: []Implementation{WordFunc(RotateLeft)}, Doc: "Rotate the bits of x left by y"},
+		{Name: "rotr", MinArgs: 2, MaxArgs: 2, Impl: wordOnly("rotr"), Modes: []Implementation{WordFunc(RotateRight)}, Doc: "Rotate the bits of x right by y"},
+
+		// Radix conversion
+		{Name: "tobase", MinArgs: 2, MaxArgs: 2, Impl: func(args []float64) (float64, error) {
+			return args[0], nil
+		}, Modes: []Implementation{ValueFunc(ToBase)}, Doc: "x written in base y, from 2 to 36"},
+		{Name: "frombase", MinArgs: 2, MaxArgs: 2, Impl: func(args []float64) (float64, error) {
+			return 0, fmt.Errorf("frombase expects a string of digits, e.g. frombase(\"ff\", 16)")
+		}, Modes: []Implementation{ValueFunc(FromBase)}, Doc: "The number with the digits x in base y, e.g. frombase(\"ff\", 16)"},
+
+		// Magnitude and rounding
+		{Name: "abs", MinArgs: 1, MaxArgs: 1, Impl: unary(Abs), Modes: []Implementation{bigUnary(BigAbs), ratUnary(RatAbs), complexUnary(ComplexAbs), IntervalFunc(IntervalAbs), derivUnary(derivAbs)}, Doc: "Absolute value"},
+		{Name: "round", MinArgs: 1, MaxArgs: 2, Impl: rounding(math.Round), Modes: []Implementation{bigRounding(bigRound), ratRounding(ratRound), complexRounding(rounding(math.Round)), intervalRounding(math.Round), DerivFunc(derivConstant), FiguresRounding}, Doc: "Round to y decimal places"},
+		{Name: "floor", MinArgs: 1, MaxArgs: 2, Impl: rounding(math.Floor), Modes: []Implementation{bigRounding(bigFloor), ratRounding(ratFloor), complexRounding(rounding(math.Floor)), intervalRounding(math.Floor), DerivFunc(derivConstant), FiguresRounding}, Doc: "Round down to y decimal places"},
+		{Name: "ceil", MinArgs: 1, MaxArgs: 2, Impl: rounding(math.Ceil), Modes: []Implementation{bigRounding(bigCeil), ratRounding(ratCeil), complexRounding(rounding(math.Ceil)), intervalRounding(math.Ceil), DerivFunc(derivConstant), FiguresRounding}, Doc: "Round up to y decimal places"},
+		{Name: "min", MinArgs: 1, MaxArgs: -1, Impl: func(args []float64) (float64, error) {
+			return Min(args...), nil
+		}, Modes: []Implementation{bigExtreme(-1), ratExtreme(-1), intervalExtreme(-1), derivExtreme(-1)}, Doc: "Smallest argument"},
+		{Name: "max", MinArgs: 1, MaxArgs: -1, Impl: func(args []float64) (float64, error) {
+			return Max(args...), nil
+		}, Modes: []Implementation{bigExtreme(1), ratExtreme(1), intervalExtreme(1), derivExtreme(1)}, Doc: "Largest argument"},
+
+		// Complex parts
+		{Name: "re", MinArgs: 1, MaxArgs: 1, Impl: unary(Re), Modes: []Implementation{bigUnary(bigCopy), ratUnary(ratCopy), complexUnary(ComplexRe), IntervalFunc(intervalCopy), DerivFunc(derivIdentity)}, Doc: "Real part"},
+		{Name: "im", MinArgs: 1, MaxArgs: 1, Impl: unary(Im), Modes: []Implementation{bigUnary(bigZero), ratUnary(ratZero), complexUnary(ComplexIm), IntervalFunc(intervalZero), DerivFunc(derivConstant)}, Doc: "Imaginary part"},
+		{Name: "conj", MinArgs: 1, MaxArgs: 1, Impl: unary(Re), Modes: []Implementation{bigUnary(bigCopy), ratUnary(ratCopy), complexUnary(cmplx.Conj), IntervalFunc(intervalCopy), DerivFunc(derivIdentity)}, Doc: "Complex conjugate"},
+		{Name: "arg", MinArgs: 1, MaxArgs: 1, Impl: unary(Arg), Modes: []Implementation{bigUnary(BigArg), complexUnary(ComplexArg), IntervalFunc(IntervalArg), DerivFunc(derivConstant)}, Doc: "Argument (phase angle)", AngleOut: true},
+
+		// Conditionals
+		{Name: "if", MinArgs: 3, MaxArgs: 3, Impl: func(args []float64) (float64, error) {
+			return If(args[0], args[1], args[2]), nil
+		}, Doc: "y if x is true (non-zero), otherwise z; only one branch is evaluated"},
+	}
+
+	for _, f := range builtins {
+		mustRegister(f)
+	}
+}
@@ -0,0 +1,129 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// FloatInfo describes how a number is stored in IEEE-754 binary64 (float64)
+// or binary32 (float32): the three bit fields, what kind of number they
+// encode, its exact value and its neighbours
+type FloatInfo struct {
+	Width    int     // 64 or 32
+	Value    float64 // the stored number; float32 values are exact in a float64
+	Sign     int     // 1 for negative numbers, including -0
+	Exponent int     // the stored exponent field, which is biased
+	Mantissa uint64  // the stored fraction field, without the implicit leading 1
+	Below    float64 // the next smaller representable number
+	Above    float64 // the next larger representable number
+}
+
+// InspectFloat64 returns the encoding of x as a float64
+func InspectFloat64(x float64) FloatInfo {
+	bits := math.Float64bits(x)
+	return FloatInfo{
+		Width:    64,
+		Value:    x,
+		Sign:     int(bits >> 63),
+		Exponent: int(bits >> 52 & 0x7FF),
+		Mantissa: bits & (1<<52 - 1),
+		Below:    math.Nextafter(x, math.Inf(-1)),
+		Above:    math.Nextafter(x, math.Inf(1)),
+	}
+}
+
+// InspectFloat32 returns the encoding of x as a float32
+func InspectFloat32(x float32) FloatInfo {
+	bits := math.Float32bits(x)
+	return FloatInfo{
+		Width:    32,
+		Value:    float64(x),
+		Sign:     int(bits >> 31),
+		Exponent: int(bits >> 23 & 0xFF),
+		Mantissa: uint64(bits & (1<<23 - 1)),
+		Below:    float64(math.Nextafter32(x, float32(math.Inf(-1)))),
+		Above:    float64(math.Nextafter32(x, float32(math.Inf(1)))),
+	}
+}
+
+// layout returns the widths of the exponent and fraction fields
+func (f FloatInfo) layout() (expBits, fracBits int) {
+	if f.Width == 32 {
+		return 8, 23
+	}
+	return 11, 52
+}
+
+// bias returns the exponent bias, 1023 for float64 and 127 for float32
+func (f FloatInfo) bias() int {
+	expBits, _ := f.layout()
+	return 1<<(expBits-1) - 1
+}
+
+// Class returns "zero", "subnormal", "normal", "infinity" or "nan"
+func (f FloatInfo) Class() string {
+	expBits, _ := f.layout()
+	switch f.Exponent {
+	case 0:
+		if f.Mantissa == 0 {
+			return "zero"
+		}
+		return "subnormal"
+	case 1<<expBits - 1:
+		if f.Mantissa == 0 {
+			return "infinity"
+		}
+		return "nan"
+	default:
+		return "normal"
+	}
+}
+
+// Power returns the power of two the mantissa is scaled by: the exponent
+// field less the bias, or the smallest normal exponent for zero and
+// subnormals, which have no implicit leading 1
+func (f FloatInfo) Power() int {
+	if f.Exponent == 0 {
+		return 1 - f.bias()
+	}
+	return f.Exponent - f.bias()
+}
+
+// Quiet reports whether a NaN is quiet, that is whether the highest bit of
+// its fraction field is set
+func (f FloatInfo) Quiet() bool {
+	_, fracBits := f.layout()
+	return f.Mantissa>>(fracBits-1) == 1
+}
+
+// Payload returns the bits of a NaN's fraction field below the quiet bit
+func (f FloatInfo) Payload() uint64 {
+	_, fracBits := f.layout()
+	return f.Mantissa &^ (1 << (fracBits - 1))
+}
+
+// Pattern returns the sign, exponent and fraction fields in binary,
+// separated by spaces
+func (f FloatInfo) Pattern() string {
+	expBits, fracBits := f.layout()
+	return fmt.Sprintf("%d %0*b %0*b", f.Sign, expBits, f.Exponent, fracBits, f.Mantissa)
+}
+
+// Exact returns every digit of the stored number in decimal, e.g.
+// 0.1000000000000000055511151231257827021181583404541015625 for 0.1. A
+// binary fraction with k bits after the point has exactly k decimal places.
+func (f FloatInfo) Exact() string {
+	switch {
+	case math.IsNaN(f.Value):
+		return "nan"
+	case math.IsInf(f.Value, 1):
+		return "inf"
+	case math.IsInf(f.Value, -1):
+		return "-inf"
+	case f.Value == 0 && f.Sign == 1:
+		return "-0"
+	}
+	r := new(big.Rat).SetFloat64(f.Value)
+	return r.FloatString(r.Denom().BitLen() - 1)
+}
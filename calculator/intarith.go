@@ -0,0 +1,352 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// intArith is the arithmetic shared by the integer modes. IntegerDomain and
+// ProgrammerDomain differ only in policy: how division rounds, and whether
+// a result too wide for the word wraps around or is an error.
+type intArith struct {
+	mode     string // the name of the mode, for errors
+	bits     int    // word size, or 0 for integers of any size
+	unsigned bool   // words hold 0 to 2^bits-1 instead of being signed
+	floor    bool   // round toward -Inf instead of toward zero
+	wrap     bool   // wrap around in two's complement instead of failing with ErrOverflow
+}
+
+// integer converts any value to an integer, rounding as division does
+func (a intArith) integer(x Value) (*big.Int, error) {
+	return roundInteger(x, a.floor)
+}
+
+// fit returns n as an Integer, wrapped into the word or, without wrap, an
+// error if it does not fit
+func (a intArith) fit(n *big.Int) (Value, error) {
+	if a.bits == 0 {
+		return NewInteger(n), nil
+	}
+	if a.wrap {
+		return NewInteger(a.wrapWord(n)), nil
+	}
+
+	// A negative n fits when -n-1 does
+	m := n
+	if n.Sign() < 0 {
+		m = new(big.Int).Not(n)
+	}
+	if m.BitLen() > a.bits-1 {
+		return nil, a.overflow()
+	}
+	return NewInteger(n), nil
+}
+
+// overflow is the error for a result that does not fit in the word
+func (a intArith) overflow() error {
+	return fmt.Errorf("%w: result does not fit in %d bits", ErrOverflow, a.bits)
+}
+
+// wrapWord wraps n into the word in two's complement, so 128 is -128 in
+// signed 8-bit words
+func (a intArith) wrapWord(n *big.Int) *big.Int {
+	if a.bits == 0 {
+		return n
+	}
+	m := a.modulus()
+	n = new(big.Int).Mod(n, m)
+	if !a.unsigned && n.Bit(a.bits-1) == 1 {
+		n.Sub(n, m)
+	}
+	return n
+}
+
+// modulus returns 2^bits
+func (a intArith) modulus() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(a.bits))
+}
+
+func (a intArith) literal(text string) (Value, error) {
+	n, err := integerLiteral(a.mode, text)
+	if err != nil {
+		return nil, err
+	}
+	return a.fit(n)
+}
+
+func (a intArith) convert(x Value) (Value, error) {
+	if _, ok := x.(Integer); ok && a.bits == 0 {
+		return x, nil
+	}
+	n, err := a.integer(x)
+	if err != nil {
+		return nil, err
+	}
+	return a.fit(n)
+}
+
+func (a intArith) bool(b bool) Value {
+	return NewInteger(big.NewInt(int64(FromBool(b))))
+}
+
+func (a intArith) truthy(x Value) bool {
+	n, err := a.integer(x)
+	return err == nil && n.Sign() != 0
+}
+
+func (a intArith) negate(x Value) (Value, error) {
+	n, err := a.integer(x)
+	if err != nil {
+		return nil, err
+	}
+	return a.fit(n.Neg(n))
+}
+
+func (a intArith) binary(op string, x, y Value) (Value, error) {
+	m, err := a.integer(x)
+	if err != nil {
+		return nil, err
+	}
+	n, err := a.integer(y)
+	if err != nil {
+		return nil, err
+	}
+
+	z := new(big.Int)
+	switch op {
+	case "+":
+		return a.fit(z.Add(m, n))
+	case "-":
+		return a.fit(z.Sub(m, n))
+	case "*":
+		return a.fit(z.Mul(m, n))
+	case "/", "%":
+		if n.Sign() == 0 {
+			if op == "%" {
+				return nil, fmt.Errorf("modulus by zero")
+			}
+			return nil, fmt.Errorf("division by zero")
+		}
+		q, r := a.divide(m, n)
+		if op == "%" {
+			return a.fit(r)
+		}
+		return a.fit(q)
+	case "^":
+		result, err := a.pow(m, n)
+		if err != nil {
+			return nil, err
+		}
+		return a.fit(result)
+	case "<", "<=", ">", ">=", "==", "!=":
+		result, err := Compare(op, float64(m.Cmp(n)), 0)
+		if err != nil {
+			return nil, err
+		}
+		return a.bool(Truthy(result)), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+func (a intArith) factorial(x Value) (Value, error) {
+	n, err := a.integer(x)
+	if err != nil {
+		return nil, err
+	}
+	if n.Sign() < 0 {
+		return nil, fmt.Errorf("factorial undefined for negative integers")
+	}
+	if !n.IsInt64() {
+		return nil, fmt.Errorf("factorial argument too large")
+	}
+	return a.fit(FactorialInt(n.Int64()))
+}
+
+func (a intArith) doubleFactorial(x Value) (Value, error) {
+	n, err := a.integer(x)
+	if err != nil {
+		return nil, err
+	}
+	if n.Cmp(big.NewInt(-1)) < 0 {
+		return nil, fmt.Errorf("double factorial undefined for non-integers and integers below -1")
+	}
+	if !n.IsInt64() {
+		return nil, fmt.Errorf("double factorial argument too large")
+	}
+	return a.fit(doubleFactorialInt(n.Int64()))
+}
+
+// call uses a function's exact integer implementation when it has one, then
+// its rational one, and otherwise its float64 implementation, as long as
+// the arguments and the result are small enough to be exact in a float64.
+// A result that is not an integer, such as sqrt(17), is an error rather
+// than being rounded.
+func (a intArith) call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		if err != nil {
+			return nil, err
+		}
+		if n, isInt := v.(Integer); isInt {
+			return a.fit(n.n)
+		}
+		return v, nil
+	}
+
+	ints := make([]*big.Int, len(args))
+	for i, arg := range args {
+		n, err := a.integer(arg)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = n
+	}
+
+	if impl, ok := ImplementationOf[RatFunc](f); ok {
+		rats := make([]*big.Rat, len(ints))
+		for i, n := range ints {
+			rats[i] = new(big.Rat).SetInt(n)
+		}
+		result, err := impl(rats)
+		switch {
+		case err == nil && result.IsInt():
+			return a.fit(new(big.Int).Set(result.Num()))
+		case err == nil:
+			return nil, a.notInteger(f, args)
+		case !errors.Is(err, ErrInexact):
+			return nil, err
+		}
+	}
+
+	floats := make([]float64, len(ints))
+	for i, n := range ints {
+		if n.CmpAbs(big.NewInt(maxExactFloat)) > 0 {
+			return nil, fmt.Errorf("%s: argument %s is too large to compute exactly in %s mode", f.Name, n, a.mode)
+		}
+		floats[i] = float64(n.Int64())
+	}
+	result, err := f.Impl(floats)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := floatInteger(result)
+	if !ok {
+		if math.Abs(result) > maxExactFloat && !math.IsInf(result, 0) {
+			return nil, fmt.Errorf("%s: result is too large to compute exactly in %s mode", f.Name, a.mode)
+		}
+		return nil, a.notInteger(f, args)
+	}
+	return a.fit(n)
+}
+
+// notInteger is the error for a call whose result is not an integer
+func (a intArith) notInteger(f Function, args []Value) error {
+	text := make([]string, len(args))
+	for i, arg := range args {
+		text[i] = arg.String()
+	}
+	return fmt.Errorf("%s(%s) is not an integer in %s mode", f.Name, strings.Join(text, ", "), a.mode)
+}
+
+// divide returns the quotient and remainder of m/n, rounding the quotient
+// toward zero or, with floor, toward -Inf. The remainder has the sign of m,
+// as with math.Mod, or with floor the sign of n.
+func (a intArith) divide(m, n *big.Int) (*big.Int, *big.Int) {
+	q, r := new(big.Int).QuoRem(m, n, new(big.Int))
+	if a.floor && r.Sign() != 0 && r.Sign() != n.Sign() {
+		q.Sub(q, big.NewInt(1))
+		r.Add(r, n)
+	}
+	return q, r
+}
+
+// pow returns m^n. A negative n only gives an integer when m is 1 or -1;
+// any other m is an error. When results wrap, only the bits that fit in a
+// word are computed; otherwise powers that cannot fit fail before they are
+// computed.
+func (a intArith) pow(m, n *big.Int) (*big.Int, error) {
+	if n.Sign() < 0 {
+		switch {
+		case m.Sign() == 0:
+			return nil, fmt.Errorf("division by zero")
+		case m.CmpAbs(big.NewInt(1)) == 0:
+			if n.Bit(0) == 1 {
+				return new(big.Int).Set(m), nil
+			}
+			return big.NewInt(1), nil
+		case m.Sign() < 0:
+			return nil, fmt.Errorf("(%s)^%s is not an integer in %s mode", m, n, a.mode)
+		default:
+			return nil, fmt.Errorf("%s^%s is not an integer in %s mode", m, n, a.mode)
+		}
+	}
+
+	if a.wrap && a.bits > 0 {
+		mod := a.modulus()
+		return new(big.Int).Exp(new(big.Int).Mod(m, mod), n, mod), nil
+	}
+	if m.CmpAbs(big.NewInt(1)) > 0 {
+		// |m^n| is at least 2^((bits of |m| - 1) * n)
+		limit := int64(maxRatBits)
+		if a.bits > 0 {
+			limit = int64(a.bits)
+		}
+		if !n.IsInt64() || n.Int64() > limit/int64(m.BitLen()-1) {
+			if a.bits > 0 {
+				return nil, a.overflow()
+			}
+			return nil, fmt.Errorf("result too large for %s mode", a.mode)
+		}
+	}
+	return new(big.Int).Exp(m, n, nil), nil
+}
+
+// roundInteger converts any value to an integer, rounding toward zero, or
+// toward -Inf if floor is set
+func roundInteger(x Value, floor bool) (*big.Int, error) {
+	if n, ok := exactInteger(x); ok {
+		return n, nil
+	}
+	if r, ok := x.(Rational); ok {
+		if floor {
+			// The denominator is positive, so Euclidean division floors
+			return new(big.Int).Div(r.r.Num(), r.r.Denom()), nil
+		}
+		return new(big.Int).Quo(r.r.Num(), r.r.Denom()), nil
+	}
+
+	f := x.Float64()
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%s is not a finite integer", Float(f))
+	}
+	if floor {
+		f = math.Floor(f)
+	}
+	n, _ := big.NewFloat(math.Trunc(f)).Int(nil)
+	return n, nil
+}
+
+// integerLiteral parses a literal of an integer mode, rejecting fractions
+func integerLiteral(mode, text string) (*big.Int, error) {
+	// Exponents are expanded exactly, so keep them to a sensible size
+	if i := strings.IndexAny(text, "eE"); i >= 0 && RadixPrefix(text) == 0 {
+		exp, err := strconv.Atoi(text[i+1:])
+		if err != nil || exp > 4096 || exp < -4096 {
+			return nil, fmt.Errorf("exponent out of range in %s mode: %s", mode, text)
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", text)
+	}
+	if !r.IsInt() {
+		return nil, fmt.Errorf("%s mode only has integers: %s", mode, text)
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
@@ -0,0 +1,75 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrOverflow is returned by IntegerDomain when a result does not fit in
+// its word size
+var ErrOverflow = errors.New("integer overflow")
+
+// IntegerDomain evaluates + - * / % ^ with exact integers, so 2^62 + 1 keeps
+// every digit instead of rounding to the nearest float64. With a word size,
+// a result that does not fit in a signed integer of that many bits is an
+// error wrapping ErrOverflow; without one, results grow as large as needed.
+// Division truncates toward zero, as in C and Go, or rounds toward -Inf, as
+// in Python, with Floor; % takes the sign of the dividend or of the divisor
+// to match. Any other result that is not an integer, such as sqrt(2) or
+// 2^-1, is an error rather than being rounded.
+type IntegerDomain struct {
+	Bits  int  // signed word size, e.g. 64, or 0 for integers of any size
+	Floor bool // / rounds toward -Inf instead of toward zero
+}
+
+func (IntegerDomain) Name() string {
+	return "integer"
+}
+
+// arith returns the integer arithmetic with this domain's policy
+func (d IntegerDomain) arith() intArith {
+	return intArith{mode: d.Name(), bits: d.Bits, floor: d.Floor}
+}
+
+func (d IntegerDomain) Literal(text string) (Value, error) {
+	return d.arith().literal(text)
+}
+
+// Constant provides no constants, since pi and e are not integers
+func (IntegerDomain) Constant(name string) (Value, bool) {
+	return nil, false
+}
+
+func (d IntegerDomain) Convert(x Value) (Value, error) {
+	return d.arith().convert(x)
+}
+
+func (d IntegerDomain) Bool(b bool) Value {
+	return d.arith().bool(b)
+}
+
+func (d IntegerDomain) Truthy(x Value) bool {
+	return d.arith().truthy(x)
+}
+
+func (d IntegerDomain) Negate(x Value) (Value, error) {
+	return d.arith().negate(x)
+}
+
+func (d IntegerDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	return d.arith().binary(op, x, y)
+}
+
+func (d IntegerDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	return d.arith().factorial(x)
+}
+
+func (d IntegerDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	return d.arith().doubleFactorial(x)
+}
+
+// Call uses a function's exact integer or rational implementation when it
+// has one, and otherwise its float64 result if that is an exact integer
+func (d IntegerDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	return d.arith().call(ctx, f, args)
+}
@@ -0,0 +1,186 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+)
+
+// IntFunc is the exact integer implementation of a function. Domains call
+// it when every argument is an integer; otherwise they use the function's
+// other implementations.
+type IntFunc func(ctx context.Context, args []*big.Int) (*big.Int, error)
+
+func (IntFunc) implementation() {}
+
+// Integer is an exact integer of any size, returned by integer functions
+// such as nCr so that every digit of the result is kept
+type Integer struct {
+	n *big.Int
+}
+
+// NewInteger wraps n, which must not be modified afterwards
+func NewInteger(n *big.Int) Integer {
+	return Integer{n: n}
+}
+
+// Int returns a copy of the underlying big.Int
+func (v Integer) Int() *big.Int {
+	return new(big.Int).Set(v.n)
+}
+
+// Float64 returns the nearest float64, or ±Inf if v is out of range
+func (v Integer) Float64() float64 {
+	f, _ := new(big.Float).SetInt(v.n).Float64()
+	return f
+}
+
+func (v Integer) String() string {
+	return v.n.String()
+}
+
+// maxExactFloat is 2^53: every integer up to it is exact in a float64, but
+// above it float64 only holds every second integer, then every fourth, ...
+const maxExactFloat = 1 << 53
+
+// exactInteger returns the value of x if it is an integer. A float64 above
+// 2^53 is not taken as one, since it has already been rounded, and neither
+// is a BigFloat with more integer bits than its precision, such as 2^64 + 1
+// at 10 digits.
+func exactInteger(x Value) (*big.Int, bool) {
+	switch v := x.(type) {
+	case integral:
+		return v.Int(), true
+	case Rational:
+		if v.r.IsInt() {
+			return v.r.Num(), true
+		}
+	case BigFloat:
+		// Below 2^prec every integer is exact, so no low bits were lost
+		if v.x.IsInt() && !v.x.IsInf() && v.x.MantExp(nil) <= int(v.x.Prec()) {
+			n, _ := v.x.Int(nil)
+			return n, true
+		}
+	default:
+		if f, ok := realFloat(x); ok {
+			return floatInteger(f)
+		}
+	}
+	return nil, false
+}
+
+// realFloat returns the float64 that a real number stored in floating point
+// is, e.g. 2 for the complex number 2+0i
+func realFloat(x Value) (float64, bool) {
+	switch v := x.(type) {
+	case integral, Rational, BigFloat:
+		return 0, false
+	case Complex:
+		return real(v), imag(v) == 0
+	case Interval:
+		return v.Lo, v.IsPoint()
+	case Uncertain:
+		return v.X, v.IsExact()
+	default:
+		return x.Float64(), true
+	}
+}
+
+// floatInteger returns f if it is an integer no larger than 2^53
+func floatInteger(f float64) (*big.Int, bool) {
+	if f != math.Trunc(f) || math.Abs(f) > maxExactFloat {
+		return nil, false
+	}
+	n, _ := big.NewFloat(f).Int(nil)
+	return n, true
+}
+
+// inexactError reports an integral float64 argument above 2^53, such as
+// 2^61 - 1, which float64 rounds to 2^61, or nil for any other argument
+func inexactError(name string, f float64) error {
+	if f != math.Trunc(f) || math.IsInf(f, 0) || math.Abs(f) <= maxExactFloat {
+		return nil
+	}
+	return fmt.Errorf("%s: argument %s not exactly representable; use integer or big mode", name, Float(f))
+}
+
+// inexactArgument is inexactError for any value: it also reports a
+// BigFloat that has been rounded to an integer at its precision
+func inexactArgument(name string, x Value) error {
+	if v, ok := x.(BigFloat); ok {
+		if _, exact := exactInteger(v); exact || !v.x.IsInt() || v.x.IsInf() {
+			return nil
+		}
+		return fmt.Errorf("%s: argument %s not exactly representable at %d digits; raise the precision or use integer mode", name, v, v.digits)
+	}
+	if f, ok := realFloat(x); ok {
+		return inexactError(name, f)
+	}
+	return nil
+}
+
+// callInt applies the IntFunc or IntValueFunc of f if it has one and every
+// argument is an integer. The result of an IntFunc is returned as an
+// Integer. An argument that float64 or big mode has rounded to an integer is
+// an error.
+func callInt(ctx context.Context, f Function, args []Value) (Value, bool, error) {
+	intImpl, hasInt := ImplementationOf[IntFunc](f)
+	valueImpl, hasValue := ImplementationOf[IntValueFunc](f)
+	if !hasInt && !hasValue {
+		return nil, false, nil
+	}
+
+	ints := make([]*big.Int, len(args))
+	for i, arg := range args {
+		n, ok := exactInteger(arg)
+		if !ok {
+			if err := inexactArgument(f.Name, arg); err != nil {
+				return nil, true, err
+			}
+			return nil, false, nil
+		}
+		ints[i] = n
+	}
+
+	if hasValue {
+		v, err := valueImpl(ctx, ints)
+		return v, true, err
+	}
+	n, err := intImpl(ctx, ints)
+	if err != nil {
+		return nil, true, err
+	}
+	return NewInteger(n), true, nil
+}
+
+// maxCachedFactorial bounds the factorials kept by FactorialInt
+const maxCachedFactorial = 1000
+
+var (
+	factorialMu    sync.Mutex
+	factorialCache = []*big.Int{big.NewInt(1)}
+)
+
+// FactorialInt returns n! exactly. Factorials up to 1000! are cached, and
+// larger ones start from the largest cached value.
+func FactorialInt(n int64) *big.Int {
+	if n < 0 {
+		return big.NewInt(0)
+	}
+
+	factorialMu.Lock()
+	defer factorialMu.Unlock()
+
+	for int64(len(factorialCache)) <= min(n, maxCachedFactorial) {
+		k := int64(len(factorialCache))
+		factorialCache = append(factorialCache, new(big.Int).Mul(factorialCache[k-1], big.NewInt(k)))
+	}
+	if n <= maxCachedFactorial {
+		return new(big.Int).Set(factorialCache[n])
+	}
+
+	rest := new(big.Int).MulRange(maxCachedFactorial+1, n)
+	return rest.Mul(rest, factorialCache[maxCachedFactorial])
+}
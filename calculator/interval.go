@@ -0,0 +1,876 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// IntervalFunc is the interval implementation of a function. The result
+// must contain f(x) for every x in the argument intervals.
+type IntervalFunc func(args []Interval) (Interval, error)
+
+func (IntervalFunc) implementation() {}
+
+// IntervalMaker is implemented by domains that accept interval literals
+// such as [9.9, 10.1]
+type IntervalMaker interface {
+	Interval(lo, hi Value) (Value, error)
+}
+
+// Interval is a closed range [Lo, Hi] of real numbers, the representation
+// used by IntervalDomain
+type Interval struct {
+	Lo, Hi float64
+}
+
+// Float64 returns the midpoint
+func (x Interval) Float64() float64 {
+	return x.Mid()
+}
+
+// Mid returns the midpoint of x, rounded to nearest
+func (x Interval) Mid() float64 {
+	if x.Lo == x.Hi {
+		return x.Lo
+	}
+	if math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) {
+		return x.Lo + x.Hi
+	}
+	// Halve first so that wide intervals cannot overflow
+	return x.Lo/2 + x.Hi/2
+}
+
+// Rad returns the radius about Mid, rounded up so that Mid() ± Rad()
+// contains x
+func (x Interval) Rad() float64 {
+	m := x.Mid()
+	return max(add(x.Hi, -m, true), add(m, -x.Lo, true))
+}
+
+// IsPoint reports whether x contains a single number
+func (x Interval) IsPoint() bool {
+	return x.Lo == x.Hi
+}
+
+// Contains reports whether v lies in x
+func (x Interval) Contains(v float64) bool {
+	return x.Lo <= v && v <= x.Hi
+}
+
+// String renders x as [lo, hi] with every digit of both bounds.
+// ParseInterval accepts the result.
+func (x Interval) String() string {
+	return "[" + strconv.FormatFloat(x.Lo, 'g', -1, 64) + ", " + strconv.FormatFloat(x.Hi, 'g', -1, 64) + "]"
+}
+
+// ParseInterval parses an interval written as [lo, hi]
+func ParseInterval(text string) (Interval, error) {
+	inner, ok := strings.CutPrefix(strings.TrimSpace(text), "[")
+	if ok {
+		inner, ok = strings.CutSuffix(inner, "]")
+	}
+	lo, hi, found := strings.Cut(inner, ",")
+	if !ok || !found {
+		return Interval{}, fmt.Errorf("invalid interval: %s", text)
+	}
+
+	a, err := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid interval: %s", text)
+	}
+	b, err := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid interval: %s", text)
+	}
+	if !(a <= b) {
+		return Interval{}, fmt.Errorf("invalid interval: %s", text)
+	}
+	return Interval{Lo: a, Hi: b}, nil
+}
+
+// point returns the interval containing only x
+func point(x float64) Interval {
+	return Interval{Lo: x, Hi: x}
+}
+
+// IntervalDomain evaluates with intervals of float64 bounds. Every
+// operation rounds its bounds outward, so the result always contains the
+// exact value for any choice of operands within their intervals.
+// Functions registered without an IntervalFunc only accept integer points,
+// through their IntFunc.
+type IntervalDomain struct{}
+
+func (IntervalDomain) Name() string {
+	return "interval"
+}
+
+func (IntervalDomain) value(x Interval) (Value, error) {
+	if math.IsNaN(x.Lo) || math.IsNaN(x.Hi) {
+		return nil, fmt.Errorf("result is undefined")
+	}
+	return x, nil
+}
+
+// interval converts any value to an Interval, enclosing values that are
+// not exactly representable
+func (IntervalDomain) interval(x Value) (Interval, error) {
+	switch v := x.(type) {
+	case Interval:
+		return v, nil
+	case Float:
+		return point(float64(v)), nil
+	case integral:
+		return encloseBig(new(big.Float).SetInt(v.Int())), nil
+	case Rational:
+		return encloseRat(v.r), nil
+	case BigFloat:
+		return encloseBig(v.x), nil
+	case Complex:
+		if !v.IsReal() {
+			return Interval{}, fmt.Errorf("complex numbers cannot be used in interval mode")
+		}
+		return point(real(v)), nil
+	case Uncertain:
+		sigma := v.Sigma()
+		return Interval{Lo: add(v.X, -sigma, false), Hi: add(v.X, sigma, true)}, nil
+	}
+	return point(x.Float64()), nil
+}
+
+// encloseRat returns the smallest interval with float64 bounds containing r
+func encloseRat(r *big.Rat) Interval {
+	f, exact := r.Float64()
+	switch {
+	case exact:
+		return point(f)
+	case math.IsInf(f, 1):
+		return Interval{Lo: math.MaxFloat64, Hi: f}
+	case math.IsInf(f, -1):
+		return Interval{Lo: f, Hi: -math.MaxFloat64}
+	case r.Cmp(new(big.Rat).SetFloat64(f)) > 0:
+		return Interval{Lo: f, Hi: step(f, true)}
+	default:
+		return Interval{Lo: step(f, false), Hi: f}
+	}
+}
+
+// encloseBig returns the smallest interval with float64 bounds containing x
+func encloseBig(x *big.Float) Interval {
+	f, acc := x.Float64()
+	switch acc {
+	case big.Below:
+		return Interval{Lo: f, Hi: step(f, true)}
+	case big.Above:
+		return Interval{Lo: step(f, false), Hi: f}
+	default:
+		return point(f)
+	}
+}
+
+// Decimal expansions of the constants, long enough to decide the rounding
+// of their float64 bounds
+var (
+	intervalPi = encloseDecimal("3.14159265358979323846264338327950288419716939937510")
+	intervalE  = encloseDecimal("2.71828182845904523536028747135266249775724709369995")
+)
+
+func encloseDecimal(text string) Interval {
+	r, _ := new(big.Rat).SetString(text)
+	return encloseRat(r)
+}
+
+func (IntervalDomain) Literal(text string) (Value, error) {
+	f, err := strconv.ParseFloat(text, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		// Literals are unsigned, so they overflow upwards or underflow to 0
+		if math.IsInf(f, 1) {
+			return Interval{Lo: math.MaxFloat64, Hi: f}, nil
+		}
+		return Interval{Lo: 0, Hi: math.SmallestNonzeroFloat64}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid number: %s", text)
+	}
+
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", text)
+	}
+	return encloseRat(r), nil
+}
+
+func (IntervalDomain) Constant(name string) (Value, bool) {
+	switch name {
+	case "pi", "π":
+		return intervalPi, true
+	case "e":
+		return intervalE, true
+	}
+	return nil, false
+}
+
+func (d IntervalDomain) Convert(x Value) (Value, error) {
+	if _, ok := x.(integral); ok {
+		// Exact integers such as the result of nCr keep every digit
+		return x, nil
+	}
+	v, err := d.interval(x)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Interval builds [lo, hi] from its bounds. Bounds that are themselves
+// intervals contribute their outer ends.
+func (d IntervalDomain) Interval(lo, hi Value) (Value, error) {
+	a, err := d.interval(lo)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.interval(hi)
+	if err != nil {
+		return nil, err
+	}
+	if a.Lo > b.Hi {
+		return nil, fmt.Errorf("interval lower bound %g is above its upper bound %g", a.Lo, b.Hi)
+	}
+	return d.value(Interval{Lo: a.Lo, Hi: b.Hi})
+}
+
+// Uncertain builds [x - sigma, x + sigma], so that 10 ± 0.1 is another way
+// to write [9.9, 10.1]
+func (d IntervalDomain) Uncertain(x, sigma Value) (Value, error) {
+	a, err := d.interval(x)
+	if err != nil {
+		return nil, err
+	}
+	s, err := d.interval(sigma)
+	if err != nil {
+		return nil, err
+	}
+	if s.Lo < 0 {
+		return nil, fmt.Errorf("uncertainty must be a non-negative number, got %s", s)
+	}
+	return d.value(Interval{Lo: add(a.Lo, -s.Hi, false), Hi: add(a.Hi, s.Hi, true)})
+}
+
+func (IntervalDomain) Bool(b bool) Value {
+	return point(FromBool(b))
+}
+
+func (d IntervalDomain) Truthy(x Value) bool {
+	v, err := d.interval(x)
+	return err != nil || v != point(0)
+}
+
+func (d IntervalDomain) Negate(x Value) (Value, error) {
+	v, err := d.interval(x)
+	if err != nil {
+		return nil, err
+	}
+	return Interval{Lo: -v.Hi, Hi: -v.Lo}, nil
+}
+
+func (d IntervalDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	a, err := d.interval(x)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.interval(y)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Interval
+	switch op {
+	case "+":
+		result = Interval{Lo: add(a.Lo, b.Lo, false), Hi: add(a.Hi, b.Hi, true)}
+	case "-":
+		result = Interval{Lo: add(a.Lo, -b.Hi, false), Hi: add(a.Hi, -b.Lo, true)}
+	case "*":
+		result = IntervalMul(a, b)
+	case "/":
+		result, err = IntervalDiv(a, b)
+	case "%":
+		result, err = IntervalMod(a, b)
+	case "^":
+		result, err = IntervalPow(a, b)
+	case "<", "<=", ">", ">=", "==", "!=":
+		truth, err := compareIntervals(op, a, b)
+		if err != nil {
+			return nil, err
+		}
+		return d.Bool(truth), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.value(result)
+}
+
+// compareIntervals decides a comparison that holds for every pair of
+// numbers from a and b, or for none. Otherwise the answer depends on where
+// in the intervals the exact values lie, which is an error.
+func compareIntervals(op string, a, b Interval) (bool, error) {
+	var always, sometimes bool
+	switch op {
+	case "<":
+		always, sometimes = a.Hi < b.Lo, a.Lo < b.Hi
+	case "<=":
+		always, sometimes = a.Hi <= b.Lo, a.Lo <= b.Hi
+	case ">":
+		always, sometimes = a.Lo > b.Hi, a.Hi > b.Lo
+	case ">=":
+		always, sometimes = a.Lo >= b.Hi, a.Hi >= b.Lo
+	case "==":
+		always, sometimes = a.IsPoint() && a == b, a.Lo <= b.Hi && b.Lo <= a.Hi
+	case "!=":
+		always, sometimes = a.Hi < b.Lo || b.Hi < a.Lo, !(a.IsPoint() && a == b)
+	}
+	if always == sometimes {
+		return always, nil
+	}
+	return false, fmt.Errorf("%s %s %s is uncertain because the intervals overlap", a, op, b)
+}
+
+func (d IntervalDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	v, err := d.interval(x)
+	if err != nil {
+		return nil, err
+	}
+	if v.IsPoint() && v.Lo == math.Trunc(v.Lo) {
+		if v.Lo < 0 {
+			return nil, fmt.Errorf("factorial undefined for negative integers")
+		}
+		if v.Lo > maxExactGamma {
+			return nil, fmt.Errorf("factorial argument too large")
+		}
+		return d.value(encloseBig(new(big.Float).SetInt(FactorialInt(int64(v.Lo)))))
+	}
+
+	// x! = Γ(x+1)
+	result, err := IntervalGamma([]Interval{{Lo: add(v.Lo, 1, false), Hi: add(v.Hi, 1, true)}})
+	if err != nil {
+		return nil, err
+	}
+	return d.value(result)
+}
+
+func (d IntervalDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	v, err := d.interval(x)
+	if err != nil {
+		return nil, err
+	}
+	if !v.IsPoint() || v.Lo != math.Trunc(v.Lo) || v.Lo < -1 {
+		return nil, fmt.Errorf("double factorial undefined for non-integers and integers below -1")
+	}
+	if v.Lo > 2*maxExactGamma {
+		return nil, fmt.Errorf("double factorial argument too large")
+	}
+	return d.value(encloseBig(new(big.Float).SetInt(doubleFactorialInt(int64(v.Lo)))))
+}
+
+// Call returns the exact result of an integer function at integer points,
+// like FloatDomain. Other arguments need the function's Interval
+// implementation.
+func (d IntervalDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		return v, err
+	}
+	impl, ok := ImplementationOf[IntervalFunc](f)
+	if !ok {
+		return nil, fmt.Errorf("%s is not supported in interval mode", f.Name)
+	}
+
+	xs := make([]Interval, len(args))
+	for i, arg := range args {
+		v, err := d.interval(arg)
+		if err != nil {
+			return nil, err
+		}
+		xs[i] = v
+	}
+
+	result, err := impl(xs)
+	if err != nil {
+		return nil, err
+	}
+	return d.value(result)
+}
+
+// step returns the float64 next to x towards +Inf if up, or -Inf
+func step(x float64, up bool) float64 {
+	if up {
+		return math.Nextafter(x, math.Inf(1))
+	}
+	return math.Nextafter(x, math.Inf(-1))
+}
+
+// directed rounds r, the float64 result of an operation whose exact result
+// is r + e, up or down. The error e is computed exactly with FMA or the
+// TwoSum algorithm; it is NaN or r is infinite after an overflow, and both
+// cases step outward.
+func directed(r, e float64, up bool) float64 {
+	if math.IsInf(r, 0) || math.IsNaN(e) || (up && e > 0) || (!up && e < 0) {
+		return step(r, up)
+	}
+	return r
+}
+
+// add returns a + b rounded up or down. Sums that fall in the subnormal
+// range are always exact.
+func add(a, b float64, up bool) float64 {
+	s := a + b
+	bb := s - a
+	return directed(s, (a-(s-bb))+(b-bb), up)
+}
+
+// mul returns a·b rounded up or down. Products that underflow do not have
+// an exact error term, so they step outward.
+func mul(a, b float64, up bool) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	p := a * b
+	if math.Abs(p) < minNormal {
+		return step(p, up)
+	}
+	return directed(p, math.FMA(a, b, -p), up)
+}
+
+// div returns a/b rounded up or down, for b != 0
+func div(a, b float64, up bool) float64 {
+	if a == 0 || math.IsInf(b, 0) {
+		return a / b
+	}
+	q := a / b
+	if math.Abs(q) < minNormal {
+		return step(q, up)
+	}
+	// The remainder a - q·b has the sign of the error for positive b
+	r := math.FMA(-q, b, a)
+	if b < 0 {
+		r = -r
+	}
+	return directed(q, r, up)
+}
+
+// sqrtDir returns √x rounded up or down, for x >= 0
+func sqrtDir(x float64, up bool) float64 {
+	s := math.Sqrt(x)
+	if x == 0 || math.IsInf(x, 0) {
+		return s
+	}
+	return directed(s, math.FMA(-s, s, x), up)
+}
+
+// minNormal is the smallest positive normal float64
+const minNormal = 0x1p-1022
+
+// libULPs is how far the results of math package functions are widened.
+// They are accurate to about one ulp but not correctly rounded.
+const libULPs = 2
+
+// libRound widens v = f(x), computed by the math package, up or down by
+// libULPs. An argument of 0 or 1 giving 0 or 1, as in sin(0), exp(0) or
+// log(1), is an exact special case and is kept.
+func libRound(x, v float64, up bool) float64 {
+	if (x == 0 || x == 1) && (v == 0 || v == 1) {
+		return v
+	}
+	return widen(v, up)
+}
+
+// widen moves v up or down by libULPs
+func widen(v float64, up bool) float64 {
+	for i := 0; i < libULPs; i++ {
+		v = step(v, up)
+	}
+	return v
+}
+
+// IntervalMul returns a·b, the hull of the four products of the bounds
+func IntervalMul(a, b Interval) Interval {
+	lo := min(mul(a.Lo, b.Lo, false), mul(a.Lo, b.Hi, false), mul(a.Hi, b.Lo, false), mul(a.Hi, b.Hi, false))
+	hi := max(mul(a.Lo, b.Lo, true), mul(a.Lo, b.Hi, true), mul(a.Hi, b.Lo, true), mul(a.Hi, b.Hi, true))
+	return Interval{Lo: lo, Hi: hi}
+}
+
+// IntervalDiv returns a/b. The divisor must not contain zero, since the
+// quotient would be unbounded.
+func IntervalDiv(a, b Interval) (Interval, error) {
+	if b.Contains(0) {
+		if b.IsPoint() {
+			return Interval{}, fmt.Errorf("division by zero")
+		}
+		return Interval{}, fmt.Errorf("division by an interval containing zero: %s", b)
+	}
+	lo := min(div(a.Lo, b.Lo, false), div(a.Lo, b.Hi, false), div(a.Hi, b.Lo, false), div(a.Hi, b.Hi, false))
+	hi := max(div(a.Lo, b.Lo, true), div(a.Lo, b.Hi, true), div(a.Hi, b.Lo, true), div(a.Hi, b.Hi, true))
+	return Interval{Lo: lo, Hi: hi}, nil
+}
+
+// IntervalMod returns a % b with the sign of a, as in float mode. When b
+// is a point and a lies within one period, the result is exact; otherwise
+// it is every value the remainder can take.
+func IntervalMod(a, b Interval) (Interval, error) {
+	if b.Contains(0) {
+		return Interval{}, fmt.Errorf("modulus by zero")
+	}
+	if b.IsPoint() && !math.IsInf(a.Lo, 0) && !math.IsInf(a.Hi, 0) {
+		// math.Mod is exact. Within one period the remainder increases with
+		// a; crossing a multiple of b would make it drop instead.
+		lo, hi := math.Mod(a.Lo, b.Lo), math.Mod(a.Hi, b.Lo)
+		sameSign := a.Lo >= 0 || a.Hi <= 0
+		if sameSign && lo <= hi && add(a.Hi, -a.Lo, true) < math.Abs(b.Lo) {
+			return Interval{Lo: lo, Hi: hi}, nil
+		}
+	}
+
+	m := max(math.Abs(b.Lo), math.Abs(b.Hi))
+	switch {
+	case a.Lo >= 0:
+		return Interval{Lo: 0, Hi: m}, nil
+	case a.Hi <= 0:
+		return Interval{Lo: -m, Hi: 0}, nil
+	default:
+		return Interval{Lo: -m, Hi: m}, nil
+	}
+}
+
+// IntervalPow returns a^b. Integer exponents accept any base and are
+// computed by repeated multiplication with directed rounding; others need
+// a non-negative base, where the power is monotonic in each argument.
+func IntervalPow(a, b Interval) (Interval, error) {
+	if b.IsPoint() && b.Lo == math.Trunc(b.Lo) && math.Abs(b.Lo) < 1<<53 {
+		n := b.Lo
+		if n == 0 {
+			return point(1), nil
+		}
+		p := intervalPowInt(a, uint64(math.Abs(n)))
+		if n < 0 {
+			return IntervalDiv(point(1), p)
+		}
+		return p, nil
+	}
+
+	if a.Lo < 0 {
+		return Interval{}, fmt.Errorf("power of an interval with negative values needs an integer exponent")
+	}
+	if a.Lo == 0 && b.Lo < 0 {
+		return Interval{}, fmt.Errorf("division by zero")
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, x := range []float64{a.Lo, a.Hi} {
+		for _, y := range []float64{b.Lo, b.Hi} {
+			v := math.Pow(x, y)
+			if (x == 0 || x == 1 || y == 0) && (v == 0 || v == 1) {
+				lo, hi = min(lo, v), max(hi, v)
+				continue
+			}
+			lo, hi = min(lo, widen(v, false)), max(hi, widen(v, true))
+		}
+	}
+	return Interval{Lo: max(lo, 0), Hi: hi}, nil
+}
+
+// intervalPowInt returns a^n for n >= 1
+func intervalPowInt(a Interval, n uint64) Interval {
+	// pow computes x^n for x >= 0, where rounding every product in the same
+	// direction bounds the result
+	pow := func(x float64, up bool) float64 {
+		result := 1.0
+		for k := n; k > 0; k >>= 1 {
+			if k&1 == 1 {
+				result = mul(result, x, up)
+			}
+			x = mul(x, x, up)
+		}
+		return result
+	}
+	// signed extends pow to negative x for odd n
+	signed := func(x float64, up bool) float64 {
+		if x < 0 {
+			return -pow(-x, !up)
+		}
+		return pow(x, up)
+	}
+
+	switch {
+	case n%2 == 1:
+		return Interval{Lo: signed(a.Lo, false), Hi: signed(a.Hi, true)}
+	case a.Lo >= 0:
+		return Interval{Lo: pow(a.Lo, false), Hi: pow(a.Hi, true)}
+	case a.Hi <= 0:
+		return Interval{Lo: pow(-a.Hi, false), Hi: pow(-a.Lo, true)}
+	default:
+		return Interval{Lo: 0, Hi: pow(max(-a.Lo, a.Hi), true)}
+	}
+}
+
+// noErr adapts a function that cannot fail for monotone and valley
+func noErr(fn func(float64) float64) func(float64) (float64, error) {
+	return func(x float64) (float64, error) {
+		return fn(x), nil
+	}
+}
+
+// monotone builds the interval version of an increasing or decreasing
+// function from its values at the bounds. Domain errors, such as log of a
+// negative number, come from fn.
+func monotone(fn func(float64) (float64, error), increasing bool) IntervalFunc {
+	return func(args []Interval) (Interval, error) {
+		x := args[0]
+		lo, err := fn(x.Lo)
+		if err != nil {
+			return Interval{}, err
+		}
+		hi, err := fn(x.Hi)
+		if err != nil {
+			return Interval{}, err
+		}
+		if increasing {
+			return Interval{Lo: libRound(x.Lo, lo, false), Hi: libRound(x.Hi, hi, true)}, nil
+		}
+		return Interval{Lo: libRound(x.Hi, hi, false), Hi: libRound(x.Lo, lo, true)}, nil
+	}
+}
+
+// valley builds the interval version of a function that decreases up to
+// xmin and increases after it, such as cosh or gamma on positive numbers
+func valley(fn func(float64) (float64, error), xmin float64) IntervalFunc {
+	return func(args []Interval) (Interval, error) {
+		x := args[0]
+		switch {
+		case x.Hi <= xmin:
+			return monotone(fn, false)(args)
+		case x.Lo >= xmin:
+			return monotone(fn, true)(args)
+		}
+
+		bottom, err := fn(xmin)
+		if err != nil {
+			return Interval{}, err
+		}
+		lo, err := fn(x.Lo)
+		if err != nil {
+			return Interval{}, err
+		}
+		hi, err := fn(x.Hi)
+		if err != nil {
+			return Interval{}, err
+		}
+		return Interval{
+			Lo: libRound(xmin, bottom, false),
+			Hi: max(libRound(x.Lo, lo, true), libRound(x.Hi, hi, true)),
+		}, nil
+	}
+}
+
+// periodic builds the interval version of sin (phase π/2) or cos (phase 0).
+// Their maxima lie at phase + 2kπ and minima at phase + (2k+1)π; an
+// extremum that may lie in the interval, allowing for the rounding of kπ,
+// is included in the result.
+func periodic(fn func(float64) float64, phase float64) IntervalFunc {
+	return func(args []Interval) (Interval, error) {
+		x := args[0]
+		full := Interval{Lo: -1, Hi: 1}
+		if math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) || x.Hi-x.Lo >= 2*Pi || math.Abs(x.Lo) > 1<<50 {
+			return full, nil
+		}
+
+		a, b := fn(x.Lo), fn(x.Hi)
+		result := Interval{
+			Lo: min(libRound(x.Lo, a, false), libRound(x.Hi, b, false)),
+			Hi: max(libRound(x.Lo, a, true), libRound(x.Hi, b, true)),
+		}
+
+		first := math.Floor((x.Lo-phase)/Pi) - 1
+		last := math.Ceil((x.Hi-phase)/Pi) + 1
+		for k := first; k <= last; k++ {
+			c := phase + k*Pi
+			tol := 1e-14 * max(1, math.Abs(c))
+			if c < x.Lo-tol || c > x.Hi+tol {
+				continue
+			}
+			if math.Mod(k, 2) == 0 {
+				result.Hi = 1
+			} else {
+				result.Lo = -1
+			}
+		}
+		return Interval{Lo: max(result.Lo, -1), Hi: min(result.Hi, 1)}, nil
+	}
+}
+
+// IntervalTan returns tan over x, which must not contain a pole
+func IntervalTan(args []Interval) (Interval, error) {
+	x := args[0]
+	if math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) || x.Hi-x.Lo >= Pi {
+		return Interval{}, fmt.Errorf("tan is unbounded on %s", x)
+	}
+	first := math.Floor((x.Lo-Pi/2)/Pi) - 1
+	last := math.Ceil((x.Hi-Pi/2)/Pi) + 1
+	for k := first; k <= last; k++ {
+		c := Pi/2 + k*Pi
+		tol := 1e-14 * max(1, math.Abs(c))
+		if c >= x.Lo-tol && c <= x.Hi+tol {
+			return Interval{}, fmt.Errorf("tan is unbounded on %s", x)
+		}
+	}
+	return monotone(noErr(Tan), true)(args)
+}
+
+// IntervalSqrt returns √x using the correctly rounded math.Sqrt
+func IntervalSqrt(args []Interval) (Interval, error) {
+	x := args[0]
+	if x.Lo < 0 {
+		return Interval{}, fmt.Errorf("square root of negative number")
+	}
+	return Interval{Lo: sqrtDir(x.Lo, false), Hi: sqrtDir(x.Hi, true)}, nil
+}
+
+// gammaMin is where Γ has its minimum on the positive reals
+const gammaMin = 1.4616321449683623
+
+// IntervalGamma returns Γ(x) for intervals of positive numbers, and at
+// integer points exactly
+func IntervalGamma(args []Interval) (Interval, error) {
+	x := args[0]
+	if x.IsPoint() && x.Lo == math.Trunc(x.Lo) && x.Lo >= 1 && x.Lo <= maxExactGamma {
+		return encloseBig(new(big.Float).SetInt(FactorialInt(int64(x.Lo) - 1))), nil
+	}
+	if x.Lo <= 0 {
+		if x.IsPoint() {
+			return intervalAt(Gamma, x.Lo)
+		}
+		return Interval{}, fmt.Errorf("gamma is only supported on positive intervals")
+	}
+	return valley(Gamma, gammaMin)(args)
+}
+
+// IntervalLgamma returns ln|Γ(x)| for intervals of positive numbers
+func IntervalLgamma(args []Interval) (Interval, error) {
+	x := args[0]
+	if x.Lo <= 0 {
+		if x.IsPoint() {
+			return intervalAt(Lgamma, x.Lo)
+		}
+		return Interval{}, fmt.Errorf("lgamma is only supported on positive intervals")
+	}
+	return valley(Lgamma, gammaMin)(args)
+}
+
+// IntervalBeta returns B(a, b) = Γ(a)Γ(b)/Γ(a+b) for positive intervals
+func IntervalBeta(args []Interval) (Interval, error) {
+	a, b := args[0], args[1]
+	if a.Lo <= 0 || b.Lo <= 0 {
+		return Interval{}, fmt.Errorf("beta is only supported on positive intervals")
+	}
+	ga, err := IntervalGamma(args[:1])
+	if err != nil {
+		return Interval{}, err
+	}
+	gb, err := IntervalGamma(args[1:])
+	if err != nil {
+		return Interval{}, err
+	}
+	gab, err := IntervalGamma([]Interval{{Lo: add(a.Lo, b.Lo, false), Hi: add(a.Hi, b.Hi, true)}})
+	if err != nil {
+		return Interval{}, err
+	}
+	return IntervalDiv(IntervalMul(ga, gb), gab)
+}
+
+// intervalAt encloses fn(x) at a single point
+func intervalAt(fn func(float64) (float64, error), x float64) (Interval, error) {
+	v, err := fn(x)
+	if err != nil {
+		return Interval{}, err
+	}
+	return Interval{Lo: libRound(x, v, false), Hi: libRound(x, v, true)}, nil
+}
+
+// IntervalAbs returns |x|
+func IntervalAbs(args []Interval) (Interval, error) {
+	x := args[0]
+	switch {
+	case x.Lo >= 0:
+		return x, nil
+	case x.Hi <= 0:
+		return Interval{Lo: -x.Hi, Hi: -x.Lo}, nil
+	default:
+		return Interval{Lo: 0, Hi: max(-x.Lo, x.Hi)}, nil
+	}
+}
+
+// intervalRounding applies a non-decreasing rounding function such as
+// floor at an optional number of decimal places. The scale 10^places is
+// exact for up to 22 places.
+func intervalRounding(fn func(float64) float64) IntervalFunc {
+	return func(args []Interval) (Interval, error) {
+		x := args[0]
+		if len(args) == 1 {
+			return Interval{Lo: fn(x.Lo), Hi: fn(x.Hi)}, nil
+		}
+
+		places := args[1]
+		if !places.IsPoint() || places.Lo != math.Trunc(places.Lo) || math.Abs(places.Lo) > 22 {
+			return Interval{}, fmt.Errorf("decimal places must be an integer between -22 and 22 in interval mode")
+		}
+		scale := math.Pow(10, math.Abs(places.Lo))
+		if places.Lo >= 0 {
+			lo, hi := fn(mul(x.Lo, scale, false)), fn(mul(x.Hi, scale, true))
+			return Interval{Lo: div(lo, scale, false), Hi: div(hi, scale, true)}, nil
+		}
+		lo, hi := fn(div(x.Lo, scale, false)), fn(div(x.Hi, scale, true))
+		return Interval{Lo: mul(lo, scale, false), Hi: mul(hi, scale, true)}, nil
+	}
+}
+
+// intervalExtreme returns the smallest argument when want is -1 and the
+// largest when want is 1, bound by bound
+func intervalExtreme(want int) IntervalFunc {
+	pick := func(a, b float64) float64 { return max(a, b) }
+	if want < 0 {
+		pick = func(a, b float64) float64 { return min(a, b) }
+	}
+	return func(args []Interval) (Interval, error) {
+		result := args[0]
+		for _, x := range args[1:] {
+			result = Interval{Lo: pick(result.Lo, x.Lo), Hi: pick(result.Hi, x.Hi)}
+		}
+		return result, nil
+	}
+}
+
+// IntervalArg returns the argument of a real interval: 0 for positive
+// numbers and π for negative ones
+func IntervalArg(args []Interval) (Interval, error) {
+	x := args[0]
+	switch {
+	case x.Lo >= 0:
+		return point(0), nil
+	case x.Hi < 0:
+		return intervalPi, nil
+	default:
+		return Interval{Lo: 0, Hi: intervalPi.Hi}, nil
+	}
+}
+
+func intervalCopy(args []Interval) (Interval, error) {
+	return args[0], nil
+}
+
+func intervalZero(args []Interval) (Interval, error) {
+	return point(0), nil
+}
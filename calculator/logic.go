@@ -0,0 +1,59 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+)
+
+// Boolean values are represented as 1 (true) and 0 (false)
+const (
+	True  = 1.0
+	False = 0.0
+)
+
+// FromBool converts a Go boolean to its numeric representation
+func FromBool(b bool) float64 {
+	if b {
+		return True
+	}
+	return False
+}
+
+// Truthy reports whether x counts as true: any value other than 0 or NaN
+func Truthy(x float64) bool {
+	return x != 0 && !math.IsNaN(x)
+}
+
+// Compare applies a comparison operator and returns 1 or 0
+func Compare(op string, a, b float64) (float64, error) {
+	switch op {
+	case "<":
+		return FromBool(a < b), nil
+	case "<=":
+		return FromBool(a <= b), nil
+	case ">":
+		return FromBool(a > b), nil
+	case ">=":
+		return FromBool(a >= b), nil
+	case "==":
+		return FromBool(a == b), nil
+	case "!=":
+		return FromBool(a != b), nil
+	default:
+		return 0, fmt.Errorf("unknown comparison operator: %s", op)
+	}
+}
+
+// Not returns the logical negation of x
+func Not(x float64) float64 {
+	return FromBool(!Truthy(x))
+}
+
+// If returns a when cond is true and b otherwise. The expression evaluator
+// treats if specially so that only the selected branch is evaluated.
+func If(cond, a, b float64) float64 {
+	if Truthy(cond) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,443 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// IntValueFunc is an integer function whose result is not a plain number,
+// such as a prime factorisation or a list of divisors
+type IntValueFunc func(ctx context.Context, args []*big.Int) (Value, error)
+
+func (IntValueFunc) implementation() {}
+
+// integral is implemented by values that stand for an exact integer
+type integral interface {
+	Int() *big.Int
+}
+
+const (
+	// maxRhoSteps bounds the Pollard rho search, so that factoring a
+	// product of two huge primes fails in seconds instead of running for
+	// hours. Factors below about 10^11 are still found.
+	maxRhoSteps = 1 << 20
+
+	// maxNextPrimeBits bounds the argument of nextprime
+	maxNextPrimeBits = 2048
+
+	// maxDivisors bounds the length of the list returned by divisors
+	maxDivisors = 100000
+)
+
+// PrimePower is a factor p^k of a prime factorisation
+type PrimePower struct {
+	Prime *big.Int
+	Exp   int
+}
+
+// Factorization is the prime factorisation of an integer, returned by
+// factor. It displays as 2^3 * 3^2 * 5 and otherwise acts as the integer.
+type Factorization struct {
+	n       *big.Int
+	factors []PrimePower
+}
+
+// Factors returns the prime powers in increasing order of prime
+func (v Factorization) Factors() []PrimePower {
+	return append([]PrimePower(nil), v.factors...)
+}
+
+// Int returns a copy of the factorised integer
+func (v Factorization) Int() *big.Int {
+	return new(big.Int).Set(v.n)
+}
+
+func (v Factorization) Float64() float64 {
+	return NewInteger(v.n).Float64()
+}
+
+func (v Factorization) String() string {
+	if len(v.factors) == 0 {
+		return v.n.String()
+	}
+
+	parts := make([]string, 0, len(v.factors)+1)
+	if v.n.Sign() < 0 {
+		parts = append(parts, "-1")
+	}
+	for _, f := range v.factors {
+		if f.Exp == 1 {
+			parts = append(parts, f.Prime.String())
+		} else {
+			parts = append(parts, fmt.Sprintf("%s^%d", f.Prime, f.Exp))
+		}
+	}
+	return strings.Join(parts, " * ")
+}
+
+// IntList is a list of integers, returned by divisors. It displays as
+// {1, 2, 3, 6}; used as a number it counts its elements.
+type IntList struct {
+	items []*big.Int
+}
+
+// Items returns the integers in the list
+func (v IntList) Items() []*big.Int {
+	items := make([]*big.Int, len(v.items))
+	for i, n := range v.items {
+		items[i] = new(big.Int).Set(n)
+	}
+	return items
+}
+
+func (v IntList) Float64() float64 {
+	return float64(len(v.items))
+}
+
+func (v IntList) String() string {
+	parts := make([]string, len(v.items))
+	for i, n := range v.items {
+		parts[i] = n.String()
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// IsPrimeInt returns 1 if n is prime and 0 otherwise. The test is exact
+// below 2^64 and wrong with negligible probability above.
+func IsPrimeInt(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	n := args[0]
+	return big.NewInt(int64(FromBool(n.Sign() > 0 && n.ProbablyPrime(20)))), nil
+}
+
+// NextPrime returns the smallest prime greater than n
+func NextPrime(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	n := args[0]
+	if n.BitLen() > maxNextPrimeBits {
+		return nil, fmt.Errorf("nextprime argument too large (maximum %d bits)", maxNextPrimeBits)
+	}
+	if n.Cmp(big.NewInt(2)) < 0 {
+		return big.NewInt(2), nil
+	}
+
+	p := new(big.Int).Add(n, big.NewInt(1))
+	if p.Bit(0) == 0 && p.Cmp(big.NewInt(2)) != 0 {
+		p.Add(p, big.NewInt(1))
+	}
+	for !p.ProbablyPrime(20) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p.Add(p, big.NewInt(2))
+	}
+	return p, nil
+}
+
+// GCD returns the greatest common divisor of its arguments, which is never
+// negative. gcd(0, 0) is 0.
+func GCD(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	result := new(big.Int).Abs(args[0])
+	for _, n := range args[1:] {
+		result.GCD(nil, nil, result, new(big.Int).Abs(n))
+	}
+	return result, nil
+}
+
+// LCM returns the least common multiple of its arguments, which is never
+// negative. It is 0 if any argument is 0.
+func LCM(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	result := new(big.Int).Abs(args[0])
+	for _, n := range args[1:] {
+		if result.Sign() == 0 || n.Sign() == 0 {
+			return new(big.Int), nil
+		}
+		a := new(big.Int).Abs(n)
+		g := new(big.Int).GCD(nil, nil, result, a)
+		result.Mul(result, a.Quo(a, g))
+	}
+	return result, nil
+}
+
+// ModPow returns b^e mod m in [0, m). A negative exponent uses the modular
+// inverse of b.
+func ModPow(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	b, e, m := args[0], args[1], args[2]
+	if m.Sign() <= 0 {
+		return nil, fmt.Errorf("modpow modulus must be positive")
+	}
+
+	base := new(big.Int).Mod(b, m)
+	if e.Sign() < 0 {
+		inv, err := ModInverse(ctx, []*big.Int{base, m})
+		if err != nil {
+			return nil, err
+		}
+		return inv.Exp(inv, new(big.Int).Neg(e), m), nil
+	}
+	return base.Exp(base, e, m), nil
+}
+
+// ModInverse returns x in [0, m) with a·x ≡ 1 (mod m)
+func ModInverse(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	a, m := args[0], args[1]
+	if m.Sign() <= 0 {
+		return nil, fmt.Errorf("modinv modulus must be positive")
+	}
+	if m.Cmp(big.NewInt(1)) == 0 {
+		return new(big.Int), nil
+	}
+
+	inv := new(big.Int).ModInverse(new(big.Int).Mod(a, m), m)
+	if inv == nil {
+		return nil, fmt.Errorf("%s has no inverse modulo %s", a, m)
+	}
+	return inv, nil
+}
+
+// Totient returns Euler's φ(n), the number of integers in [1, n] coprime
+// to n
+func Totient(ctx context.Context, args []*big.Int) (*big.Int, error) {
+	n := args[0]
+	if n.Sign() <= 0 {
+		return nil, fmt.Errorf("totient expects a positive integer")
+	}
+
+	factors, err := FactorInt(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	result := new(big.Int).Set(n)
+	for _, f := range factors {
+		// φ(n) = n Π (1 - 1/p)
+		result.Quo(result, f.Prime)
+		result.Mul(result, new(big.Int).Sub(f.Prime, big.NewInt(1)))
+	}
+	return result, nil
+}
+
+// Factor returns the prime factorisation of n as a Factorization
+func Factor(ctx context.Context, args []*big.Int) (Value, error) {
+	n := args[0]
+	if n.Sign() == 0 {
+		return nil, fmt.Errorf("factor undefined for 0")
+	}
+
+	factors, err := FactorInt(ctx, new(big.Int).Abs(n))
+	if err != nil {
+		return nil, err
+	}
+	return Factorization{n: new(big.Int).Set(n), factors: factors}, nil
+}
+
+// Divisors returns the positive divisors of n in increasing order
+func Divisors(ctx context.Context, args []*big.Int) (Value, error) {
+	n := new(big.Int).Abs(args[0])
+	if n.Sign() == 0 {
+		return nil, fmt.Errorf("divisors undefined for 0")
+	}
+
+	factors, err := FactorInt(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 1
+	for _, f := range factors {
+		count *= f.Exp + 1
+		if count > maxDivisors {
+			return nil, fmt.Errorf("%s has more than %d divisors", n, maxDivisors)
+		}
+	}
+
+	divs := []*big.Int{big.NewInt(1)}
+	for _, f := range factors {
+		size := len(divs)
+		power := big.NewInt(1)
+		for k := 1; k <= f.Exp; k++ {
+			power = new(big.Int).Mul(power, f.Prime)
+			for _, d := range divs[:size] {
+				divs = append(divs, new(big.Int).Mul(d, power))
+			}
+		}
+	}
+	sort.Slice(divs, func(i, j int) bool {
+		return divs[i].Cmp(divs[j]) < 0
+	})
+	return IntList{items: divs}, nil
+}
+
+// smallPrimes are used for trial division before Pollard rho
+var smallPrimes = func() []int64 {
+	const limit = 1000
+	composite := make([]bool, limit)
+	var primes []int64
+	for i := 2; i < limit; i++ {
+		if !composite[i] {
+			primes = append(primes, int64(i))
+			for j := i * i; j < limit; j += i {
+				composite[j] = true
+			}
+		}
+	}
+	return primes
+}()
+
+// FactorInt returns the prime factorisation of n >= 1 in increasing order of
+// prime. Small factors are found by trial division and the rest with
+// Pollard's rho method, which gives up on products of very large primes.
+func FactorInt(ctx context.Context, n *big.Int) ([]PrimePower, error) {
+	if n.Sign() <= 0 {
+		return nil, fmt.Errorf("factorisation needs a positive integer")
+	}
+
+	counts := make(map[string]int)
+	primes := make(map[string]*big.Int)
+	add := func(p *big.Int) {
+		key := p.String()
+		counts[key]++
+		primes[key] = p
+	}
+
+	m := new(big.Int).Set(n)
+	rem := new(big.Int)
+	q := new(big.Int)
+	for _, sp := range smallPrimes {
+		p := big.NewInt(sp)
+		for {
+			q.QuoRem(m, p, rem)
+			if rem.Sign() != 0 {
+				break
+			}
+			add(p)
+			m.Set(q)
+		}
+	}
+
+	// Split the remaining cofactor until every piece is prime
+	pending := []*big.Int{m}
+	for len(pending) > 0 {
+		c := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		switch {
+		case c.Cmp(big.NewInt(1)) == 0:
+		case c.ProbablyPrime(20):
+			add(c)
+		default:
+			d, err := pollardRho(ctx, c)
+			if err != nil {
+				return nil, err
+			}
+			if d == nil && c.Cmp(n) == 0 {
+				return nil, fmt.Errorf("could not fully factor %s: its prime factors are too large to find", n)
+			}
+			if d == nil {
+				return nil, fmt.Errorf("could not fully factor %s: the factor %s is composite, but its prime factors are too large to find", n, c)
+			}
+			pending = append(pending, d, new(big.Int).Quo(c, d))
+		}
+	}
+
+	factors := make([]PrimePower, 0, len(counts))
+	for key, k := range counts {
+		factors = append(factors, PrimePower{Prime: primes[key], Exp: k})
+	}
+	sort.Slice(factors, func(i, j int) bool {
+		return factors[i].Prime.Cmp(factors[j].Prime) < 0
+	})
+	return factors, nil
+}
+
+// pollardRho returns a non-trivial factor of the composite n using Brent's
+// variant of Pollard's rho method, or nil if none is found within
+// maxRhoSteps. A different polynomial is tried when a cycle yields only n.
+// The context is checked after every batch of steps.
+func pollardRho(ctx context.Context, n *big.Int) (*big.Int, error) {
+	const batch = 128
+	one := big.NewInt(1)
+
+	for c := int64(1); c <= 5; c++ {
+		cc := big.NewInt(c)
+		step := func(x *big.Int) *big.Int {
+			x.Mul(x, x)
+			x.Add(x, cc)
+			return x.Mod(x, n)
+		}
+
+		y := big.NewInt(2)
+		x := new(big.Int)
+		ys := new(big.Int)
+		q := big.NewInt(1)
+		g := big.NewInt(1)
+		diff := new(big.Int)
+
+		for r := 1; g.Cmp(one) == 0 && r <= maxRhoSteps; r *= 2 {
+			x.Set(y)
+			for i := 0; i < r; i++ {
+				if i%batch == 0 {
+					if err := ctx.Err(); err != nil {
+						return nil, err
+					}
+				}
+				step(y)
+			}
+			// Multiply batches of |x - y| together to save gcds
+			for k := 0; k < r && g.Cmp(one) == 0; k += batch {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				ys.Set(y)
+				for i := 0; i < min(batch, r-k); i++ {
+					step(y)
+					q.Mul(q, diff.Abs(diff.Sub(x, y)))
+					q.Mod(q, n)
+				}
+				g.GCD(nil, nil, q, n)
+			}
+		}
+		if g.Cmp(one) == 0 {
+			return nil, nil
+		}
+
+		if g.Cmp(n) == 0 {
+			// The batch overshot; retrace it one step at a time
+			for i := 0; i < batch; i++ {
+				step(ys)
+				g.GCD(nil, nil, diff.Abs(diff.Sub(x, ys)), n)
+				if g.Cmp(one) != 0 {
+					break
+				}
+			}
+		}
+		if g.Cmp(one) != 0 && g.Cmp(n) != 0 {
+			return g, nil
+		}
+	}
+	return nil, nil
+}
+
+// intValueFloat is intFloat for functions with a structured result; the
+// float64 value is the result's Float64
+func intValueFloat(name string, fn IntValueFunc) FloatFunc {
+	return func(args []float64) (float64, error) {
+		ints := make([]*big.Int, len(args))
+		for i, x := range args {
+			n, ok := floatInteger(x)
+			if !ok {
+				if err := inexactError(name, x); err != nil {
+					return 0, err
+				}
+				return 0, fmt.Errorf("%s expects integer arguments", name)
+			}
+			ints[i] = n
+		}
+
+		v, err := fn(context.Background(), ints)
+		if err != nil {
+			return 0, err
+		}
+		return v.Float64(), nil
+	}
+}
@@ -0,0 +1,91 @@
+package calculator
+
+import (
+	"context"
+	"math/big"
+)
+
+// ProgrammerDomain evaluates with integers, as a programmer's calculator
+// does. Literals may be written in hex (0xFF), octal (0o17) or binary
+// (0b1010) as well as in decimal. Division truncates toward zero, as in C
+// and Go; any other result that is not an integer, such as 2^-1 or sqrt(2),
+// is an error rather than being rounded. With a word size, every result wraps around in
+// two's complement, so 0x7F + 1 is -128 in signed 8-bit words. The zero
+// value works with signed integers of any size.
+type ProgrammerDomain struct {
+	Bits     int  // word size: 8, 16, 32 or 64, or 0 for integers of any size
+	Unsigned bool // words hold 0 to 2^Bits-1; ignored without a word size
+}
+
+func (ProgrammerDomain) Name() string {
+	return "programmer"
+}
+
+// arith returns the integer arithmetic with this domain's policy
+func (d ProgrammerDomain) arith() intArith {
+	return intArith{mode: d.Name(), bits: d.Bits, unsigned: d.Unsigned, wrap: true}
+}
+
+// integer converts any value to an integer, truncating toward zero
+func (d ProgrammerDomain) integer(x Value) (*big.Int, error) {
+	return d.arith().integer(x)
+}
+
+func (d ProgrammerDomain) Literal(text string) (Value, error) {
+	return d.arith().literal(text)
+}
+
+// Constant provides no constants, since pi and e are not integers
+func (ProgrammerDomain) Constant(name string) (Value, bool) {
+	return nil, false
+}
+
+func (d ProgrammerDomain) Convert(x Value) (Value, error) {
+	return d.arith().convert(x)
+}
+
+func (d ProgrammerDomain) Bool(b bool) Value {
+	return d.arith().bool(b)
+}
+
+func (d ProgrammerDomain) Truthy(x Value) bool {
+	return d.arith().truthy(x)
+}
+
+func (d ProgrammerDomain) Negate(x Value) (Value, error) {
+	return d.arith().negate(x)
+}
+
+func (d ProgrammerDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	return d.arith().binary(op, x, y)
+}
+
+func (d ProgrammerDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	return d.arith().factorial(x)
+}
+
+func (d ProgrammerDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	return d.arith().doubleFactorial(x)
+}
+
+// Call uses a function's word, exact integer or rational implementation
+// when it has one, and otherwise its float64 result if that is an exact
+// integer
+func (d ProgrammerDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if impl, ok := ImplementationOf[WordFunc](f); ok {
+		ints := make([]*big.Int, len(args))
+		for i, arg := range args {
+			n, err := d.integer(arg)
+			if err != nil {
+				return nil, err
+			}
+			ints[i] = n
+		}
+		n, err := impl(d, ints)
+		if err != nil {
+			return nil, err
+		}
+		return d.word(n), nil
+	}
+	return d.arith().call(ctx, f, args)
+}
@@ -0,0 +1,342 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ValueFunc is the implementation of a function that works on values as
+// they are instead of on numbers of the current domain, such as tobase,
+// which needs the exact value of its argument, or frombase, which takes a
+// string. The evaluator calls it in every domain.
+type ValueFunc func(d Domain, args []Value) (Value, error)
+
+func (ValueFunc) implementation() {}
+
+// radixDigits are the digits of bases up to 36
+const radixDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// maxRadixDigits bounds the fractional digits shown by FormatRadix, so that
+// 1/97 in base 10, whose digits repeat every 96, is cut short
+const maxRadixDigits = 64
+
+// Text is a string argument, such as the digits passed to frombase. It
+// only appears as the argument of a function with a ValueFunc.
+type Text string
+
+func (t Text) Float64() float64 {
+	return math.NaN()
+}
+
+func (t Text) String() string {
+	return strconv.Quote(string(t))
+}
+
+// RadixNumber is a number written in another base, returned by tobase. It
+// displays as its digits in that base, e.g. 0.0(0011) for 0.1 in base 2,
+// and otherwise acts as the number.
+type RadixNumber struct {
+	r    *big.Rat
+	base int
+}
+
+// Rat returns a copy of the exact value of the number
+func (v RadixNumber) Rat() *big.Rat {
+	return new(big.Rat).Set(v.r)
+}
+
+// Base returns the base the number is shown in
+func (v RadixNumber) Base() int {
+	return v.base
+}
+
+func (v RadixNumber) Float64() float64 {
+	f, _ := v.r.Float64()
+	return f
+}
+
+func (v RadixNumber) String() string {
+	return FormatRadix(v.r, v.base)
+}
+
+// ExactRat returns the value of a real number as a fraction. Floating-point
+// numbers are read as the fraction they most likely stand for, so 0.1 is
+// 1/10 rather than the binary fraction nearest to it, and 1/3 computed in
+// float64 is 1/3; any other float is its exact binary value.
+func ExactRat(x Value) (*big.Rat, error) {
+	switch v := x.(type) {
+	case Rational:
+		return new(big.Rat).Set(v.r), nil
+	case RadixNumber:
+		return v.Rat(), nil
+	case BigFloat:
+		if v.x.IsInf() {
+			return nil, fmt.Errorf("%s is not a finite number", v)
+		}
+		return simplestRat(v.x), nil
+	case Complex:
+		if imag(v) != 0 {
+			return nil, fmt.Errorf("%s is not a real number", v)
+		}
+	case Interval:
+		if !v.IsPoint() {
+			return nil, fmt.Errorf("%s is not a single number", v)
+		}
+	case Uncertain:
+		if !v.IsExact() {
+			return nil, fmt.Errorf("%s is not an exact number", v)
+		}
+	}
+
+	if n, ok := exactInteger(x); ok {
+		return new(big.Rat).SetInt(n), nil
+	}
+	f := x.Float64()
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%s is not a finite number", Float(f))
+	}
+	if f == math.Trunc(f) {
+		// Above 2^53 the stored integer is all there is to go on
+		r, _ := new(big.Rat).SetString(big.NewFloat(f).Text('f', 0))
+		return r, nil
+	}
+	return simplestRat(big.NewFloat(f)), nil
+}
+
+// simplestRat returns the fraction with the smallest denominator that
+// rounds to x at its precision, if that denominator is small next to the
+// precision, as for 1/3. Otherwise it returns the exact value of x, such as
+// 2^-30 or the 53 bits of sqrt(2).
+func simplestRat(x *big.Float) *big.Rat {
+	r, _ := x.Rat(nil)
+	if r.Sign() == 0 {
+		return r
+	}
+
+	// x rounds from anywhere within half a unit in the last place
+	exp := x.MantExp(nil) - int(x.Prec()) - 1
+	half := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), uint(max(-exp, 0))))
+	if exp > 0 {
+		half.SetInt(new(big.Int).Lsh(big.NewInt(1), uint(exp)))
+	}
+
+	abs := new(big.Rat).Abs(r)
+	lo := new(big.Rat).Sub(abs, half)
+	hi := new(big.Rat).Add(abs, half)
+	s := simplestBetween(lo, hi)
+	if s.Denom().BitLen() > int(x.Prec())/2 {
+		return r
+	}
+	if r.Sign() < 0 {
+		s.Neg(s)
+	}
+	return s
+}
+
+// simplestBetween returns the fraction with the smallest denominator
+// strictly between a and b, where 0 <= a < b, by building its continued
+// fraction one term at a time
+func simplestBetween(a, b *big.Rat) *big.Rat {
+	n := new(big.Int).Quo(a.Num(), a.Denom())
+	next := new(big.Rat).SetInt(new(big.Int).Add(n, big.NewInt(1)))
+	if next.Cmp(b) < 0 {
+		return next
+	}
+
+	// Otherwise n <= a < b <= n+1, and the answer is n + 1/y for the
+	// simplest y between 1/(b-n) and 1/(a-n)
+	whole := new(big.Rat).SetInt(n)
+	fa := new(big.Rat).Sub(a, whole)
+	fb := new(big.Rat).Sub(b, whole)
+	var y *big.Rat
+	if fa.Sign() == 0 {
+		// y is only bounded below, so take the next integer
+		inv := new(big.Rat).Inv(fb)
+		y = new(big.Rat).SetInt(new(big.Int).Add(new(big.Int).Quo(inv.Num(), inv.Denom()), big.NewInt(1)))
+	} else {
+		y = simplestBetween(new(big.Rat).Inv(fb), new(big.Rat).Inv(fa))
+	}
+	return whole.Add(whole, y.Inv(y))
+}
+
+// FormatRadix writes r in a base from 2 to 36 with the digits 0-9 and a-z.
+// Fractional digits that repeat are put in parentheses, so 1/3 in base 10
+// is 0.(3); digits that have not repeated after maxRadixDigits are cut
+// short with "...".
+func FormatRadix(r *big.Rat, base int) string {
+	sign := ""
+	if r.Sign() < 0 {
+		sign = "-"
+	}
+	den := r.Denom()
+	rem := new(big.Int)
+	whole, _ := new(big.Int).QuoRem(new(big.Int).Abs(r.Num()), den, rem)
+	s := sign + whole.Text(base)
+
+	// Long division: each remainder determines every digit after it, so
+	// the digits repeat from the first remainder seen twice
+	var digits []byte
+	seen := make(map[string]int)
+	b := big.NewInt(int64(base))
+	digit := new(big.Int)
+	for rem.Sign() != 0 {
+		key := rem.String()
+		if i, ok := seen[key]; ok {
+			return s + "." + string(digits[:i]) + "(" + string(digits[i:]) + ")"
+		}
+		if len(digits) == maxRadixDigits {
+			return s + "." + string(digits) + "..."
+		}
+		seen[key] = len(digits)
+
+		rem.Mul(rem, b)
+		digit.QuoRem(rem, den, rem)
+		digits = append(digits, radixDigits[digit.Int64()])
+	}
+	if len(digits) == 0 {
+		return s
+	}
+	return s + "." + string(digits)
+}
+
+// ParseRadix reads a number written in a base from 2 to 36, such as "ff"
+// in base 16 or "-0.011" in base 2. Letters may be in either case, and
+// fractional digits in parentheses at the end repeat, as FormatRadix
+// writes them, so "0.(1)" in base 3 is 1/2.
+func ParseRadix(text string, base int) (*big.Rat, error) {
+	s := strings.ToLower(strings.TrimSpace(text))
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	repeat := ""
+	if i := strings.IndexByte(s, '('); i >= 0 && strings.HasSuffix(s, ")") && strings.Contains(s[:i], ".") {
+		s, repeat = s[:i], s[i+1:len(s)-1]
+		if repeat == "" {
+			return nil, fmt.Errorf("no repeating digits in %q", text)
+		}
+	}
+	if s == "" || (s == "." && repeat == "") {
+		return nil, fmt.Errorf("no digits in %q", text)
+	}
+
+	num := new(big.Int)
+	den := big.NewInt(1)
+	b := big.NewInt(int64(base))
+	point := false
+	for _, ch := range s {
+		if ch == '.' {
+			if point {
+				return nil, fmt.Errorf("more than one point in %q", text)
+			}
+			point = true
+			continue
+		}
+		d := strings.IndexRune(radixDigits, ch)
+		if d < 0 || d >= base {
+			return nil, fmt.Errorf("invalid digit %q for base %d", ch, base)
+		}
+		num.Mul(num, b).Add(num, big.NewInt(int64(d)))
+		if point {
+			den.Mul(den, b)
+		}
+	}
+	r := new(big.Rat).SetFrac(num, den)
+
+	// m repeating digits with value p add p / (den * (base^m - 1))
+	if repeat != "" {
+		p := new(big.Int)
+		period := big.NewInt(1)
+		for _, ch := range repeat {
+			d := strings.IndexRune(radixDigits, ch)
+			if d < 0 || d >= base {
+				return nil, fmt.Errorf("invalid digit %q for base %d", ch, base)
+			}
+			p.Mul(p, b).Add(p, big.NewInt(int64(d)))
+			period.Mul(period, b)
+		}
+		period.Sub(period, big.NewInt(1))
+		r.Add(r, new(big.Rat).SetFrac(p, period.Mul(period, den)))
+	}
+
+	if neg {
+		r.Neg(r)
+	}
+	return r, nil
+}
+
+// RadixPrefix returns the base selected by a 0x, 0o or 0b prefix at the
+// start of text, or 0 if there is none. The prefix must be followed by a
+// digit of its base, so 0b alone is still 0 times b.
+func RadixPrefix(text string) int {
+	if len(text) < 3 || text[0] != '0' {
+		return 0
+	}
+
+	base := 0
+	switch text[1] {
+	case 'x', 'X':
+		base = 16
+	case 'o', 'O':
+		base = 8
+	case 'b', 'B':
+		base = 2
+	}
+	if d := strings.IndexByte(radixDigits, lowerASCII(text[2])); base == 0 || d < 0 || d >= base {
+		return 0
+	}
+	return base
+}
+
+// lowerASCII returns the lower case of an ASCII letter, and c otherwise
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// radixBase checks the base argument of tobase and frombase
+func radixBase(x Value) (int, error) {
+	n, ok := exactInteger(x)
+	if !ok || n.Cmp(big.NewInt(2)) < 0 || n.Cmp(big.NewInt(36)) > 0 {
+		return 0, fmt.Errorf("base must be an integer from 2 to 36")
+	}
+	return int(n.Int64()), nil
+}
+
+// ToBase returns x written in base n
+func ToBase(d Domain, args []Value) (Value, error) {
+	base, err := radixBase(args[1])
+	if err != nil {
+		return nil, err
+	}
+	r, err := ExactRat(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("tobase: %v", err)
+	}
+	return RadixNumber{r: r, base: base}, nil
+}
+
+// FromBase reads the digits of a number written in base n, e.g.
+// frombase("zz", 36) is 1295
+func FromBase(d Domain, args []Value) (Value, error) {
+	text, ok := args[0].(Text)
+	if !ok {
+		return nil, fmt.Errorf("frombase expects a string of digits, e.g. frombase(\"ff\", 16)")
+	}
+	base, err := radixBase(args[1])
+	if err != nil {
+		return nil, err
+	}
+	r, err := ParseRadix(string(text), base)
+	if err != nil {
+		return nil, err
+	}
+	if r.IsInt() {
+		return d.Convert(NewInteger(new(big.Int).Set(r.Num())))
+	}
+	return d.Convert(NewRational(r))
+}
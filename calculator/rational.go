@@ -0,0 +1,390 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInexact is returned by a RatFunc whose result is not rational, e.g.
+// sqrt(2). RatDomain then falls back to float64.
+var ErrInexact = errors.New("result is not rational")
+
+// RatFunc is the exact rational implementation of a function
+type RatFunc func(args []*big.Rat) (*big.Rat, error)
+
+func (RatFunc) implementation() {}
+
+// maxRatBits bounds the size of numerators and denominators produced by
+// powers, so that 10^1000000000 fails instead of exhausting memory
+const maxRatBits = 1 << 20
+
+// Rational is an exact fraction produced by RatDomain
+type Rational struct {
+	r *big.Rat
+}
+
+// NewRational wraps r, which must not be modified afterwards
+func NewRational(r *big.Rat) Rational {
+	return Rational{r: r}
+}
+
+// Rat returns a copy of the underlying big.Rat
+func (v Rational) Rat() *big.Rat {
+	return new(big.Rat).Set(v.r)
+}
+
+func (v Rational) Float64() float64 {
+	f, _ := v.r.Float64()
+	return f
+}
+
+// String returns the reduced fraction, e.g. 7/2, or an integer
+func (v Rational) String() string {
+	return v.r.RatString()
+}
+
+// Mixed returns the value as a mixed number, e.g. 3 1/2 or -1 1/4
+func (v Rational) Mixed() string {
+	if v.r.IsInt() {
+		return v.r.RatString()
+	}
+
+	num := new(big.Int).Abs(v.r.Num())
+	whole, rem := new(big.Int).QuoRem(num, v.r.Denom(), new(big.Int))
+	if whole.Sign() == 0 {
+		return v.r.RatString()
+	}
+
+	sign := ""
+	if v.r.Sign() < 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s%s %s/%s", sign, whole, rem, v.r.Denom())
+}
+
+// RatDomain evaluates exactly with big.Rat. Operators and functions with a
+// RatFunc give exact results; anything irrational, such as pi or
+// sqrt(2), falls back to float64 and yields a Float. Arithmetic on a Float
+// stays in float64, even when it holds a whole number.
+type RatDomain struct{}
+
+func (RatDomain) Name() string {
+	return "rational"
+}
+
+// rat returns the exact value of x, or false if x is not rational
+func (RatDomain) rat(x Value) (*big.Rat, bool) {
+	switch v := x.(type) {
+	case Rational:
+		return v.r, true
+	case RadixNumber:
+		return v.r, true
+	case integral:
+		return new(big.Rat).SetInt(v.Int()), true
+	case BigFloat:
+		r, _ := v.x.Rat(nil)
+		return r, r != nil
+	}
+	// A Float is an approximation even when it is a whole number, such as
+	// floor(pi) or a rounded sqrt(10^20 + 1), so it stays one
+	return nil, false
+}
+
+func (RatDomain) Literal(text string) (Value, error) {
+	// Exponents are expanded exactly, so keep them to a sensible size
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		exp, err := strconv.Atoi(text[i+1:])
+		if err != nil || exp > 4096 || exp < -4096 {
+			return nil, fmt.Errorf("exponent out of range in rational mode: %s", text)
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", text)
+	}
+	return NewRational(r), nil
+}
+
+func (RatDomain) Constant(name string) (Value, bool) {
+	// pi and e are irrational
+	return FloatDomain{}.Constant(name)
+}
+
+func (d RatDomain) Convert(x Value) (Value, error) {
+	if r, ok := d.rat(x); ok {
+		return NewRational(r), nil
+	}
+	return Float(x.Float64()), nil
+}
+
+func (RatDomain) Bool(b bool) Value {
+	return NewRational(big.NewRat(int64(FromBool(b)), 1))
+}
+
+func (d RatDomain) Truthy(x Value) bool {
+	if r, ok := d.rat(x); ok {
+		return r.Sign() != 0
+	}
+	return Truthy(x.Float64())
+}
+
+func (d RatDomain) Negate(x Value) (Value, error) {
+	if r, ok := d.rat(x); ok {
+		return NewRational(new(big.Rat).Neg(r)), nil
+	}
+	return FloatDomain{}.Negate(x)
+}
+
+func (d RatDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	a, okA := d.rat(x)
+	b, okB := d.rat(y)
+	if !okA || !okB {
+		return FloatDomain{}.Binary(ctx, op, x, y)
+	}
+
+	z := new(big.Rat)
+	switch op {
+	case "+":
+		return NewRational(z.Add(a, b)), nil
+	case "-":
+		return NewRational(z.Sub(a, b)), nil
+	case "*":
+		return NewRational(z.Mul(a, b)), nil
+	case "/":
+		if b.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return NewRational(z.Quo(a, b)), nil
+	case "%":
+		if b.Sign() == 0 {
+			return nil, fmt.Errorf("modulus by zero")
+		}
+		// Like math.Mod, the result has the sign of a
+		q := z.Quo(a, b)
+		t := new(big.Int).Quo(q.Num(), q.Denom())
+		q.SetInt(t)
+		return NewRational(q.Sub(a, q.Mul(q, b))), nil
+	case "^":
+		result, err := RatPow(a, b)
+		if errors.Is(err, ErrInexact) {
+			return FloatDomain{}.Binary(ctx, op, x, y)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewRational(result), nil
+	case "<", "<=", ">", ">=", "==", "!=":
+		result, err := Compare(op, float64(a.Cmp(b)), 0)
+		if err != nil {
+			return nil, err
+		}
+		return d.Bool(Truthy(result)), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+func (d RatDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	r, ok := d.rat(x)
+	if !ok {
+		return FloatDomain{}.Factorial(ctx, x)
+	}
+	if !r.IsInt() {
+		// Γ(x+1) is irrational for non-integers
+		return FloatDomain{}.Factorial(ctx, x)
+	}
+	if r.Sign() < 0 {
+		return nil, fmt.Errorf("factorial undefined for negative integers")
+	}
+	if !r.Num().IsInt64() {
+		return nil, fmt.Errorf("factorial argument too large")
+	}
+	return NewRational(new(big.Rat).SetInt(FactorialInt(r.Num().Int64()))), nil
+}
+
+func (d RatDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	r, ok := d.rat(x)
+	if !ok {
+		return FloatDomain{}.DoubleFactorial(ctx, x)
+	}
+	if !r.IsInt() || r.Cmp(big.NewRat(-1, 1)) < 0 {
+		return nil, fmt.Errorf("double factorial undefined for non-integers and integers below -1")
+	}
+	if !r.Num().IsInt64() {
+		return nil, fmt.Errorf("double factorial argument too large")
+	}
+	return NewRational(new(big.Rat).SetInt(doubleFactorialInt(r.Num().Int64()))), nil
+}
+
+func (d RatDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		if n, isInt := v.(Integer); isInt {
+			return NewRational(new(big.Rat).SetInt(n.n)), nil
+		}
+		return v, err
+	}
+	impl, ok := ImplementationOf[RatFunc](f)
+	if !ok {
+		return FloatDomain{}.Call(ctx, f, args)
+	}
+
+	rats := make([]*big.Rat, len(args))
+	for i, arg := range args {
+		r, ok := d.rat(arg)
+		if !ok {
+			return FloatDomain{}.Call(ctx, f, args)
+		}
+		rats[i] = r
+	}
+
+	result, err := impl(rats)
+	if errors.Is(err, ErrInexact) {
+		return FloatDomain{}.Call(ctx, f, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewRational(result), nil
+}
+
+// RatPow returns x^y when the result is rational. Integer exponents are
+// always exact; a fractional exponent p/q is exact when x is a perfect q-th
+// power, e.g. (9/4)^(1/2) = 3/2. Otherwise it returns ErrInexact.
+func RatPow(x, y *big.Rat) (*big.Rat, error) {
+	if !y.IsInt() {
+		q := y.Denom()
+		if x.Sign() < 0 || !q.IsInt64() || q.Int64() > 64 {
+			return nil, ErrInexact
+		}
+		k := int(q.Int64())
+		num, ok := intRoot(x.Num(), k)
+		if !ok {
+			return nil, ErrInexact
+		}
+		den, ok := intRoot(x.Denom(), k)
+		if !ok {
+			return nil, ErrInexact
+		}
+		x = new(big.Rat).SetFrac(num, den)
+		y = new(big.Rat).SetInt(y.Num())
+	}
+
+	if !y.Num().IsInt64() {
+		return nil, fmt.Errorf("exponent too large")
+	}
+	n := y.Num().Int64()
+	if x.Sign() == 0 {
+		if n < 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if n == 0 {
+			return big.NewRat(1, 1), nil
+		}
+		return new(big.Rat), nil
+	}
+
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	bits := max(x.Num().BitLen(), x.Denom().BitLen())
+	if bits > 1 && abs > maxRatBits/int64(bits-1) {
+		return nil, fmt.Errorf("result too large for rational mode")
+	}
+
+	e := big.NewInt(abs)
+	num := new(big.Int).Exp(x.Num(), e, nil)
+	den := new(big.Int).Exp(x.Denom(), e, nil)
+	if n < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// RatAbs returns the absolute value of x
+func RatAbs(x *big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Abs(x), nil
+}
+
+// RatSqrt returns the square root of x when it is rational
+func RatSqrt(x *big.Rat) (*big.Rat, error) {
+	if x.Sign() < 0 {
+		return nil, fmt.Errorf("square root of negative number")
+	}
+	return RatPow(x, big.NewRat(1, 2))
+}
+
+// RatCbrt returns the cube root of x when it is rational
+func RatCbrt(x *big.Rat) (*big.Rat, error) {
+	root, err := RatPow(new(big.Rat).Abs(x), big.NewRat(1, 3))
+	if err != nil {
+		return nil, err
+	}
+	if x.Sign() < 0 {
+		root.Neg(root)
+	}
+	return root, nil
+}
+
+// intRoot returns the k-th root of a non-negative integer and whether it is
+// exact
+func intRoot(a *big.Int, k int) (*big.Int, bool) {
+	if a.Sign() == 0 || k == 1 {
+		return new(big.Int).Set(a), true
+	}
+
+	var root *big.Int
+	if k == 2 {
+		root = new(big.Int).Sqrt(a)
+	} else {
+		// Newton's method from an over-estimate converges downwards
+		kk := big.NewInt(int64(k))
+		km1 := big.NewInt(int64(k - 1))
+		root = new(big.Int).Lsh(big.NewInt(1), uint(a.BitLen()/k+1))
+		for {
+			// next = ((k-1)·x + a / x^(k-1)) / k
+			p := new(big.Int).Exp(root, km1, nil)
+			next := new(big.Int).Quo(a, p)
+			next.Add(next, new(big.Int).Mul(km1, root))
+			next.Quo(next, kk)
+			if next.Cmp(root) >= 0 {
+				break
+			}
+			root = next
+		}
+	}
+
+	check := new(big.Int).Exp(root, big.NewInt(int64(k)), nil)
+	return root, check.Cmp(a) == 0
+}
+
+// ratFloor returns the largest integer not greater than x
+func ratFloor(x *big.Rat) *big.Int {
+	// Euclidean division rounds towards negative infinity for positive divisors
+	q, _ := new(big.Int).DivMod(x.Num(), x.Denom(), new(big.Int))
+	return q
+}
+
+// ratCeil returns the smallest integer not less than x
+func ratCeil(x *big.Rat) *big.Int {
+	q := ratFloor(x)
+	if !x.IsInt() {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// ratRound rounds x to the nearest integer, halves away from zero
+func ratRound(x *big.Rat) *big.Int {
+	a := new(big.Rat).Abs(x)
+	r := ratFloor(a.Add(a, big.NewRat(1, 2)))
+	if x.Sign() < 0 {
+		r.Neg(r)
+	}
+	return r
+}
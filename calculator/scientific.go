@@ -1,7 +1,6 @@
 package calculator
 
 import (
-	"fmt"
 	"math"
 )
 
@@ -21,14 +20,14 @@ func Tan(x float64) float64 {
 // Inverse trigonometric
 func Asin(x float64) (float64, error) {
 	if x < -1 || x > 1 {
-		return 0, fmt.Errorf("asin input must be between -1 and 1")
+		return 0, domainError(math.NaN(), "asin input must be between -1 and 1")
 	}
 	return math.Asin(x), nil
 }
 
 func Acos(x float64) (float64, error) {
 	if x < -1 || x > 1 {
-		return 0, fmt.Errorf("acos input must be between -1 and 1")
+		return 0, domainError(math.NaN(), "acos input must be between -1 and 1")
 	}
 	return math.Acos(x), nil
 }
@@ -53,7 +52,7 @@ func Tanh(x float64) float64 {
 // Roots and logarithms
 func Sqrt(x float64) (float64, error) {
 	if x < 0 {
-		return 0, fmt.Errorf("square root of negative number")
+		return 0, domainError(math.NaN(), "square root of negative number")
 	}
 	return math.Sqrt(x), nil
 }
@@ -64,14 +63,14 @@ func Cbrt(x float64) float64 {
 
 func Log(x float64) (float64, error) {
 	if x <= 0 {
-		return 0, fmt.Errorf("logarithm undefined for non-positive numbers")
+		return 0, domainError(math.Log(x), "logarithm undefined for non-positive numbers")
 	}
 	return math.Log(x), nil
 }
 
 func Log10(x float64) (float64, error) {
 	if x <= 0 {
-		return 0, fmt.Errorf("log10 undefined for non-positive numbers")
+		return 0, domainError(math.Log10(x), "log10 undefined for non-positive numbers")
 	}
 	return math.Log10(x), nil
 }
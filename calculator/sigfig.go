@@ -0,0 +1,362 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FigureRule is how the result of a function inherits significant figures
+// in SigFigDomain
+type FigureRule int
+
+func (FigureRule) implementation() {}
+
+const (
+	// FiguresFromArgs keeps as many figures as the least precise argument
+	FiguresFromArgs FigureRule = iota
+	// FiguresFromFirst keeps the figures of the first argument; the others,
+	// such as the exponent of pow, are treated as exact
+	FiguresFromFirst
+	// FiguresRounding is for round, floor and ceil: the result is known to
+	// the coarser of the argument's last digit and the rounding place
+	FiguresRounding
+	// FiguresLog gives the result as many decimal places as the argument
+	// has significant figures
+	FiguresLog
+	// FiguresAntilog gives the result as many significant figures as the
+	// argument has decimal places
+	FiguresAntilog
+)
+
+// SigFig is a measured value together with the significant figures it
+// carries, the representation used by SigFigDomain. Figures is 0 for an
+// exact value such as pi. Place is the decimal exponent of the last
+// significant digit: -2 for 2.50, 2 for 1.2e3.
+type SigFig struct {
+	X       float64 `json:"x"`
+	Figures int     `json:"figures"`
+	Place   int     `json:"place"`
+}
+
+// IsExact reports whether x has no limit on its significant figures
+func (x SigFig) IsExact() bool {
+	return x.Figures == 0
+}
+
+func (x SigFig) Float64() float64 {
+	return x.X
+}
+
+// String renders x rounded to its significant figures, marking trailing
+// zeros as significant with a decimal point ("120.") or by switching to
+// scientific notation ("1.2e+04"). ParseSigFig reads back the same figures.
+// Exact values are shown with every digit.
+func (x SigFig) String() string {
+	switch {
+	case math.IsNaN(x.X):
+		return "nan"
+	case math.IsInf(x.X, 1):
+		return "inf"
+	case math.IsInf(x.X, -1):
+		return "-inf"
+	case x.IsExact():
+		return strconv.FormatFloat(x.X, 'g', -1, 64)
+	}
+
+	lead := leadingExponent(roundToPlace(x.X, x.Place))
+	if x.Place > 0 || lead < -4 {
+		figures := max(lead-x.Place+1, 1)
+		return strconv.FormatFloat(x.X, 'e', figures-1, 64)
+	}
+
+	text := strconv.FormatFloat(x.X, 'f', -x.Place, 64)
+	if x.Place == 0 && strings.HasSuffix(text, "0") {
+		// 120 would read as two figures
+		text += "."
+	}
+	if strings.Trim(text, "-0.") == "" {
+		text = strings.TrimPrefix(text, "-")
+	}
+	return text
+}
+
+// ParseSigFig parses a number and counts its significant figures by the
+// usual rules: leading zeros never count, and trailing zeros only count
+// when there is a decimal point, so 2.50 has 3, 0.0025 has 2, 100 has 1
+// and 100. has 3.
+func ParseSigFig(text string) (SigFig, error) {
+	x, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return SigFig{}, fmt.Errorf("invalid number: %s", text)
+	}
+
+	mantissa, exponent := text, 0
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		mantissa = text[:i]
+		if exponent, err = strconv.Atoi(text[i+1:]); err != nil {
+			return SigFig{}, fmt.Errorf("invalid number: %s", text)
+		}
+	}
+	mantissa = strings.TrimLeft(mantissa, "+-")
+
+	whole, fraction, hasPoint := strings.Cut(mantissa, ".")
+	digits := strings.TrimLeft(whole+fraction, "0")
+	place := exponent - len(fraction)
+	if !hasPoint {
+		trimmed := strings.TrimRight(digits, "0")
+		place += len(digits) - len(trimmed)
+		digits = trimmed
+	}
+
+	figures := len(digits)
+	if figures == 0 {
+		// A zero such as 0.00 is known to its last decimal place
+		figures = 1
+	}
+	return SigFig{X: x, Figures: figures, Place: place}, nil
+}
+
+// SigFigDomain evaluates with float64 and tracks the significant figures of
+// every value. Literals carry the figures written in the input; products
+// and quotients keep the fewest figures of their operands, and sums and
+// differences are known to the coarsest decimal place. Results are only
+// rounded for display, so intermediate rounding cannot accumulate.
+type SigFigDomain struct{}
+
+func (SigFigDomain) Name() string {
+	return "sigfig"
+}
+
+// sigFig converts any value to a SigFig. Values from other domains are exact.
+func (SigFigDomain) sigFig(x Value) SigFig {
+	if v, ok := x.(SigFig); ok {
+		return v
+	}
+	return SigFig{X: x.Float64()}
+}
+
+func (SigFigDomain) Literal(text string) (Value, error) {
+	if _, err := (FloatDomain{}).Literal(text); err != nil {
+		return nil, err
+	}
+	return ParseSigFig(text)
+}
+
+func (SigFigDomain) Constant(name string) (Value, bool) {
+	switch name {
+	case "pi", "π":
+		return SigFig{X: Pi}, true
+	case "e":
+		return SigFig{X: E}, true
+	}
+	return nil, false
+}
+
+func (d SigFigDomain) Convert(x Value) (Value, error) {
+	if _, ok := x.(integral); ok {
+		// Exact integers such as the result of nCr keep every digit
+		return x, nil
+	}
+	return d.sigFig(x), nil
+}
+
+func (SigFigDomain) Special(f float64) Value {
+	return SigFig{X: f}
+}
+
+func (SigFigDomain) Bool(b bool) Value {
+	return SigFig{X: FromBool(b)}
+}
+
+func (SigFigDomain) Truthy(x Value) bool {
+	return x.Float64() != 0
+}
+
+func (d SigFigDomain) Negate(x Value) (Value, error) {
+	v := d.sigFig(x)
+	v.X = -v.X
+	return v, nil
+}
+
+func (d SigFigDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	a, b := d.sigFig(x), d.sigFig(y)
+
+	switch op {
+	case "+":
+		return withPlace(a.X+b.X, a, b), nil
+	case "-":
+		return withPlace(a.X-b.X, a, b), nil
+	case "*":
+		return withFigures(a.X*b.X, a, b), nil
+	case "/":
+		// As in FloatDomain, x/0 is left for the evaluator to reject
+		return withFigures(a.X/b.X, a, b), nil
+	case "%":
+		// a % b is a - kb, so it is known to the place of a and b
+		return withPlace(math.Mod(a.X, b.X), a, b), nil
+	case "^":
+		r := Power(a.X, b.X)
+		if a.IsExact() && !b.IsExact() {
+			// 10^2.5 is known to as many figures as 2.5 has decimals
+			return antilog(r, b), nil
+		}
+		// The exponent counts as exact, so x^2 keeps the figures of x
+		return withFigures(r, a), nil
+	case "<", "<=", ">", ">=", "==", "!=":
+		result, err := Compare(op, a.X, b.X)
+		return SigFig{X: result}, err
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+// Factorial treats an integer as a count, so its factorial is exact
+func (d SigFigDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	v := d.sigFig(x)
+	result, err := Factorial(v.X)
+	if err != nil {
+		return nil, err
+	}
+	if v.X == math.Trunc(v.X) {
+		return SigFig{X: result}, nil
+	}
+	return withFigures(result, v), nil
+}
+
+func (d SigFigDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	result, err := DoubleFactorial(x.Float64())
+	if err != nil {
+		return nil, err
+	}
+	return SigFig{X: result}, nil
+}
+
+// Call applies the function to the values and gives the result the
+// significant figures required by its FigureRule
+func (d SigFigDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		return v, err
+	}
+
+	values := make([]SigFig, len(args))
+	for i, arg := range args {
+		values[i] = d.sigFig(arg)
+	}
+	result, err := f.Impl(Float64s(args))
+	if err != nil {
+		return nil, err
+	}
+
+	rule, _ := ImplementationOf[FigureRule](f)
+	switch rule {
+	case FiguresFromFirst:
+		return withFigures(result, values[0]), nil
+	case FiguresRounding:
+		x := values[0]
+		places := 0
+		if len(values) > 1 {
+			places = int(values[1].X)
+		}
+		if x.IsExact() {
+			return SigFig{X: result}, nil
+		}
+		return withPlace(result, x, SigFig{X: result, Figures: 1, Place: -places}), nil
+	case FiguresLog:
+		x := values[0]
+		if x.IsExact() {
+			return SigFig{X: result}, nil
+		}
+		// The mantissa of log10(2.50) = 0.398 has as many digits as 2.50
+		place := -x.Figures
+		return SigFig{X: result, Figures: figuresAt(result, place), Place: place}, nil
+	case FiguresAntilog:
+		return antilog(result, values[0]), nil
+	default:
+		return withFigures(result, values...), nil
+	}
+}
+
+// withFigures is the rule for products and quotients: x keeps the fewest
+// significant figures of the inexact operands
+func withFigures(x float64, operands ...SigFig) SigFig {
+	figures := 0
+	for _, v := range operands {
+		if !v.IsExact() && (figures == 0 || v.Figures < figures) {
+			figures = v.Figures
+		}
+	}
+	if figures == 0 {
+		return SigFig{X: x}
+	}
+	return SigFig{X: x, Figures: figures, Place: placeOf(x, figures)}
+}
+
+// withPlace is the rule for sums and differences: x is known to the
+// coarsest last decimal place of the inexact operands
+func withPlace(x float64, operands ...SigFig) SigFig {
+	place, found := 0, false
+	for _, v := range operands {
+		if !v.IsExact() && (!found || v.Place > place) {
+			place, found = v.Place, true
+		}
+	}
+	if !found {
+		return SigFig{X: x}
+	}
+	return SigFig{X: x, Figures: figuresAt(x, place), Place: place}
+}
+
+// antilog gives x, the result of e^y or 10^y, as many significant figures
+// as y has decimal places
+func antilog(x float64, y SigFig) SigFig {
+	if y.IsExact() {
+		return SigFig{X: x}
+	}
+	figures := max(-y.Place, 1)
+	return SigFig{X: x, Figures: figures, Place: placeOf(x, figures)}
+}
+
+// placeOf returns the decimal place of the last of the given number of
+// significant figures of x, after rounding, so 9.96 to two figures is 10
+func placeOf(x float64, figures int) int {
+	if x == 0 || math.IsInf(x, 0) || math.IsNaN(x) {
+		return 1 - figures
+	}
+	text := strconv.FormatFloat(x, 'e', figures-1, 64)
+	exp, _ := strconv.Atoi(text[strings.IndexByte(text, 'e')+1:])
+	return exp - figures + 1
+}
+
+// figuresAt returns how many significant figures x has when it is known
+// to the given decimal place. A result smaller than that place, such as
+// 1.00 - 0.999, still keeps one figure.
+func figuresAt(x float64, place int) int {
+	rounded := roundToPlace(x, place)
+	if rounded == 0 || math.IsInf(rounded, 0) || math.IsNaN(rounded) {
+		return 1
+	}
+	return max(leadingExponent(rounded)-place+1, 1)
+}
+
+// roundToPlace rounds x to a multiple of 10^place
+func roundToPlace(x float64, place int) float64 {
+	if place <= 0 {
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(x, 'f', -place, 64), 64)
+		return rounded
+	}
+	scale := math.Pow(10, float64(place))
+	return math.Round(x/scale) * scale
+}
+
+// leadingExponent returns the decimal exponent of the first digit of x
+func leadingExponent(x float64) int {
+	if x == 0 || math.IsInf(x, 0) || math.IsNaN(x) {
+		return 0
+	}
+	text := strconv.FormatFloat(x, 'e', -1, 64)
+	exp, _ := strconv.Atoi(text[strings.IndexByte(text, 'e')+1:])
+	return exp
+}
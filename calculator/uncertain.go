@@ -0,0 +1,510 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// DerivFunc returns the partial derivatives of a function with respect to
+// each of its arguments. UncertainDomain uses them to propagate
+// uncertainties; functions without one are differentiated numerically.
+type DerivFunc func(args []float64) ([]float64, error)
+
+func (DerivFunc) implementation() {}
+
+// UncertainMaker is implemented by domains that accept values written with
+// an uncertainty, such as 5.03 ± 0.02
+type UncertainMaker interface {
+	Uncertain(x, sigma Value) (Value, error)
+}
+
+// Uncertain is a value with a standard uncertainty, the representation used
+// by UncertainDomain. It keeps the contribution of every independent
+// measurement it was computed from, so that x - x is exactly 0 and an error
+// that enters a result twice is not counted as two independent ones.
+type Uncertain struct {
+	X     float64
+	parts map[uint64]float64 // measurement -> ∂X/∂measurement · σ(measurement)
+}
+
+// uncertainSources numbers the measurements created by NewUncertain
+var uncertainSources atomic.Uint64
+
+// NewUncertain returns x ± sigma as a new measurement, independent of every
+// other value
+func NewUncertain(x, sigma float64) Uncertain {
+	if sigma == 0 {
+		return Uncertain{X: x}
+	}
+	return Uncertain{X: x, parts: map[uint64]float64{uncertainSources.Add(1): sigma}}
+}
+
+func (u Uncertain) Float64() float64 {
+	return u.X
+}
+
+// Sigma returns the standard uncertainty, the root sum of squares of the
+// contributions of each measurement
+func (u Uncertain) Sigma() float64 {
+	sources := make([]uint64, 0, len(u.parts))
+	for id := range u.parts {
+		sources = append(sources, id)
+	}
+	// Sum in a fixed order so that the last digit does not vary between runs
+	slices.Sort(sources)
+
+	sigma := 0.0
+	for _, id := range sources {
+		sigma = math.Hypot(sigma, u.parts[id])
+	}
+	return sigma
+}
+
+// IsExact reports whether u has no uncertainty
+func (u Uncertain) IsExact() bool {
+	return len(u.parts) == 0
+}
+
+// String renders u as "x ± sigma" with every digit, or as a plain number
+// when it is exact. ParseUncertain accepts the result.
+func (u Uncertain) String() string {
+	x := strconv.FormatFloat(u.X, 'g', -1, 64)
+	if u.IsExact() {
+		return x
+	}
+	return x + " ± " + strconv.FormatFloat(u.Sigma(), 'g', -1, 64)
+}
+
+// ParseUncertain parses a value written as "x ± sigma" or as a plain
+// number. The result is a new measurement.
+func ParseUncertain(text string) (Uncertain, error) {
+	value, uncertainty, found := strings.Cut(text, "±")
+	x, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return Uncertain{}, fmt.Errorf("invalid uncertain value: %s", text)
+	}
+	if !found {
+		return Uncertain{X: x}, nil
+	}
+
+	sigma, err := strconv.ParseFloat(strings.TrimSpace(uncertainty), 64)
+	if err != nil || !(sigma >= 0) || math.IsInf(sigma, 0) {
+		return Uncertain{}, fmt.Errorf("invalid uncertain value: %s", text)
+	}
+	return NewUncertain(x, sigma), nil
+}
+
+// UncertainDomain evaluates values with uncertainties using first-order
+// (linear) propagation: the uncertainty of f(x, y) combines σx·∂f/∂x and
+// σy·∂f/∂y, taking into account which measurements x and y share.
+// Functions supply their derivatives with a DerivFunc.
+type UncertainDomain struct{}
+
+func (UncertainDomain) Name() string {
+	return "uncertain"
+}
+
+// uncertain converts any value to an Uncertain. Values from other domains
+// are exact.
+func (UncertainDomain) uncertain(x Value) (Uncertain, error) {
+	switch v := x.(type) {
+	case Uncertain:
+		return v, nil
+	case Complex:
+		if !v.IsReal() {
+			return Uncertain{}, fmt.Errorf("complex numbers cannot be used in uncertainty mode")
+		}
+	case Interval:
+		if !v.IsPoint() {
+			return Uncertain{}, fmt.Errorf("intervals cannot be used in uncertainty mode; write %s ± %s instead",
+				strconv.FormatFloat(v.Mid(), 'g', -1, 64), strconv.FormatFloat(v.Rad(), 'g', -1, 64))
+		}
+	}
+	return Uncertain{X: x.Float64()}, nil
+}
+
+func (UncertainDomain) Special(f float64) Value {
+	return Uncertain{X: f}
+}
+
+func (UncertainDomain) Literal(text string) (Value, error) {
+	x, err := FloatDomain{}.Literal(text)
+	if err != nil {
+		return nil, err
+	}
+	return Uncertain{X: x.Float64()}, nil
+}
+
+func (UncertainDomain) Constant(name string) (Value, bool) {
+	switch name {
+	case "pi", "π":
+		return Uncertain{X: Pi}, true
+	case "e":
+		return Uncertain{X: E}, true
+	}
+	return nil, false
+}
+
+func (d UncertainDomain) Convert(x Value) (Value, error) {
+	if _, ok := x.(integral); ok {
+		// Exact integers such as the result of nCr keep every digit
+		return x, nil
+	}
+	v, err := d.uncertain(x)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Uncertain returns x ± sigma. sigma must be exact; x may already carry an
+// uncertainty, in which case sigma is added as another independent one.
+func (d UncertainDomain) Uncertain(x, sigma Value) (Value, error) {
+	a, err := d.uncertain(x)
+	if err != nil {
+		return nil, err
+	}
+	s, err := d.uncertain(sigma)
+	if err != nil {
+		return nil, err
+	}
+	if !s.IsExact() {
+		return nil, fmt.Errorf("the uncertainty after ± must be an exact number")
+	}
+	if !(s.X >= 0) || math.IsInf(s.X, 0) {
+		return nil, fmt.Errorf("uncertainty must be a non-negative number, got %g", s.X)
+	}
+	return combine(a.X, term{a, 1}, term{NewUncertain(0, s.X), 1})
+}
+
+func (UncertainDomain) Bool(b bool) Value {
+	return Uncertain{X: FromBool(b)}
+}
+
+func (UncertainDomain) Truthy(x Value) bool {
+	return x.Float64() != 0
+}
+
+func (d UncertainDomain) Negate(x Value) (Value, error) {
+	a, err := d.uncertain(x)
+	if err != nil {
+		return nil, err
+	}
+	return combine(-a.X, term{a, -1})
+}
+
+func (d UncertainDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	a, err := d.uncertain(x)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.uncertain(y)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "+":
+		return combine(a.X+b.X, term{a, 1}, term{b, 1})
+	case "-":
+		return combine(a.X-b.X, term{a, 1}, term{b, -1})
+	case "*":
+		return combine(a.X*b.X, term{a, b.X}, term{b, a.X})
+	case "/":
+		q, err := Divide(a.X, b.X)
+		if err != nil {
+			return nil, err
+		}
+		return combine(q, term{a, 1 / b.X}, term{b, -q / b.X})
+	case "%":
+		r, err := Modulus(a.X, b.X)
+		if err != nil {
+			return nil, err
+		}
+		return combine(r, term{a, 1}, term{b, -math.Trunc(a.X / b.X)})
+	case "^":
+		r := Power(a.X, b.X)
+		da, db := powDerivs(a, b, r)
+		return checked("^", []float64{a.X, b.X}, r, term{a, da}, term{b, db})
+	case "<", "<=", ">", ">=", "==", "!=":
+		// Comparisons use the central values
+		result, err := Compare(op, a.X, b.X)
+		return Uncertain{X: result}, err
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+// powDerivs returns ∂/∂a and ∂/∂b of r = a^b, leaving out the ones that
+// are not needed because the operand is exact. (-8)^(1/3) has no
+// derivative with respect to the exponent, but needs none when it is exact.
+func powDerivs(a, b Uncertain, r float64) (da, db float64) {
+	if !a.IsExact() {
+		if b.X == 0 {
+			da = 0
+		} else {
+			da = b.X * math.Pow(a.X, b.X-1)
+		}
+	}
+	if !b.IsExact() {
+		db = r * math.Log(a.X)
+	}
+	return da, db
+}
+
+func (d UncertainDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	a, err := d.uncertain(x)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Factorial(a.X)
+	if err != nil {
+		return nil, err
+	}
+	if a.IsExact() {
+		return Uncertain{X: f}, nil
+	}
+
+	// x! = Γ(x+1), whose derivative is Γ(x+1)ψ(x+1)
+	psi, err := Digamma(a.X + 1)
+	if err != nil {
+		return nil, err
+	}
+	return checked("!", []float64{a.X}, f, term{a, f * psi})
+}
+
+func (d UncertainDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	a, err := d.uncertain(x)
+	if err != nil {
+		return nil, err
+	}
+	if !a.IsExact() {
+		return nil, fmt.Errorf("double factorial needs an exact integer, not a value with an uncertainty")
+	}
+	result, err := DoubleFactorial(a.X)
+	if err != nil {
+		return nil, err
+	}
+	return Uncertain{X: result}, nil
+}
+
+// Call applies the function to the central values and propagates the
+// uncertainties of the arguments through its derivatives
+func (d UncertainDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		return v, err
+	}
+
+	values := make([]Uncertain, len(args))
+	exact := true
+	for i, arg := range args {
+		v, err := d.uncertain(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+		exact = exact && v.IsExact()
+	}
+
+	xs := Float64s(args)
+	result, err := f.Impl(xs)
+	if err != nil {
+		return nil, err
+	}
+	if exact {
+		return Uncertain{X: result}, nil
+	}
+
+	var derivs []float64
+	if deriv, ok := ImplementationOf[DerivFunc](f); ok {
+		derivs, err = deriv(xs)
+	} else {
+		derivs, err = numericDerivs(f.Impl, xs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	terms := make([]term, len(values))
+	for i, v := range values {
+		terms[i] = term{v, derivs[i]}
+	}
+	return checked(f.Name, xs, result, terms...)
+}
+
+// numericDerivs estimates the partial derivatives of fn by central
+// differences, for functions registered without a DerivFunc
+func numericDerivs(fn FloatFunc, args []float64) ([]float64, error) {
+	derivs := make([]float64, len(args))
+	shifted := slices.Clone(args)
+	for i, x := range args {
+		// The cube root of the machine epsilon balances truncation and
+		// rounding error for a central difference
+		h := 6e-6 * math.Max(1, math.Abs(x))
+
+		shifted[i] = x + h
+		up, err := fn(shifted)
+		if err != nil {
+			return nil, err
+		}
+		shifted[i] = x - h
+		down, err := fn(shifted)
+		if err != nil {
+			return nil, err
+		}
+		shifted[i] = x
+
+		derivs[i] = (up - down) / (2 * h)
+	}
+	return derivs, nil
+}
+
+// term is an operand of an operation together with the derivative of the
+// result with respect to it
+type term struct {
+	u     Uncertain
+	deriv float64
+}
+
+// combine returns x with the uncertainty contributed by each term
+func combine(x float64, terms ...term) (Value, error) {
+	if math.IsNaN(x) {
+		return nil, fmt.Errorf("result is undefined")
+	}
+
+	var parts map[uint64]float64
+	for _, t := range terms {
+		if t.deriv == 0 {
+			continue
+		}
+		for id, c := range t.u.parts {
+			if parts == nil {
+				parts = make(map[uint64]float64)
+			}
+			parts[id] += t.deriv * c
+		}
+	}
+	for id, c := range parts {
+		// Contributions that cancel, as in x - x, leave no uncertainty
+		if c == 0 {
+			delete(parts, id)
+		}
+	}
+	if len(parts) == 0 {
+		parts = nil
+	}
+	return Uncertain{X: x, parts: parts}, nil
+}
+
+// checked is combine for functions whose derivative may not exist, such as
+// sqrt at 0. name and args describe the call for the error message.
+func checked(name string, args []float64, x float64, terms ...term) (Value, error) {
+	for _, t := range terms {
+		if !t.u.IsExact() && (math.IsNaN(t.deriv) || math.IsInf(t.deriv, 0)) {
+			text := make([]string, len(args))
+			for i, a := range args {
+				text[i] = strconv.FormatFloat(a, 'g', -1, 64)
+			}
+			return nil, fmt.Errorf("cannot propagate the uncertainty through %s: it has no derivative at %s", name, strings.Join(text, ", "))
+		}
+	}
+	return combine(x, terms...)
+}
+
+// derivUnary adapts the derivative of a single-argument function to a
+// DerivFunc
+func derivUnary(fn func(float64) float64) DerivFunc {
+	return func(args []float64) ([]float64, error) {
+		return []float64{fn(args[0])}, nil
+	}
+}
+
+// derivUnaryErr adapts a derivative that can fail to a DerivFunc
+func derivUnaryErr(fn func(float64) (float64, error)) DerivFunc {
+	return func(args []float64) ([]float64, error) {
+		d, err := fn(args[0])
+		return []float64{d}, err
+	}
+}
+
+// derivConstant is the derivative of a function that is constant where it
+// is differentiable, such as floor. Only the first argument is a value; the
+// others are settings such as the number of decimal places.
+func derivConstant(args []float64) ([]float64, error) {
+	return make([]float64, len(args)), nil
+}
+
+// derivIdentity is the derivative of f(x) = x
+func derivIdentity(args []float64) ([]float64, error) {
+	return []float64{1}, nil
+}
+
+// derivExtreme is the derivative of min (want -1) and max (want 1): 1 for
+// the argument selected, 0 for the others
+func derivExtreme(want int) DerivFunc {
+	return func(args []float64) ([]float64, error) {
+		best := 0
+		for i, x := range args {
+			if (want < 0 && x < args[best]) || (want > 0 && x > args[best]) {
+				best = i
+			}
+		}
+		derivs := make([]float64, len(args))
+		derivs[best] = 1
+		return derivs, nil
+	}
+}
+
+// derivPow is the derivative of pow(x, y)
+func derivPow(args []float64) ([]float64, error) {
+	x, y := args[0], args[1]
+	dx := 0.0
+	if y != 0 {
+		dx = y * math.Pow(x, y-1)
+	}
+	return []float64{dx, math.Pow(x, y) * math.Log(x)}, nil
+}
+
+// derivGamma is Γ'(x) = Γ(x)ψ(x)
+func derivGamma(x float64) (float64, error) {
+	psi, err := Digamma(x)
+	if err != nil {
+		return 0, err
+	}
+	return math.Gamma(x) * psi, nil
+}
+
+// derivBeta is the derivative of B(x, y), which is B(x, y)(ψ(x) - ψ(x+y))
+// with respect to x and symmetrically for y
+func derivBeta(args []float64) ([]float64, error) {
+	b, err := Beta(args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	psiX, err := Digamma(args[0])
+	if err != nil {
+		return nil, err
+	}
+	psiY, err := Digamma(args[1])
+	if err != nil {
+		return nil, err
+	}
+	psiSum, err := Digamma(args[0] + args[1])
+	if err != nil {
+		return nil, err
+	}
+	return []float64{b * (psiX - psiSum), b * (psiY - psiSum)}, nil
+}
+
+// derivAbs is the sign of x, which has no derivative at 0
+func derivAbs(x float64) float64 {
+	if x == 0 {
+		return math.NaN()
+	}
+	return math.Copysign(1, x)
+}
@@ -0,0 +1,197 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Value is a number produced by evaluating an expression. Each Domain has
+// its own representation; Float64 converts any of them to the nearest float64.
+type Value interface {
+	Float64() float64
+	String() string
+}
+
+// Float is a float64 value, the representation used by FloatDomain
+type Float float64
+
+func (x Float) Float64() float64 {
+	return float64(x)
+}
+
+func (x Float) String() string {
+	return strconv.FormatFloat(float64(x), 'g', -1, 64)
+}
+
+// Domain is a number system expressions can be evaluated in, such as
+// float64 or arbitrary-precision arithmetic. The evaluator handles syntax,
+// variables and control flow; the domain supplies the arithmetic. Operations
+// that can run for long, such as factorising or summing a series to
+// thousands of digits, stop with ctx's error once ctx is done.
+type Domain interface {
+	// Name identifies the domain in settings, e.g. "float" or "big"
+	Name() string
+
+	// Literal converts a number as written in the input, e.g. "0.1"
+	Literal(text string) (Value, error)
+
+	// Constant returns a named constant such as pi, computed in the domain
+	Constant(name string) (Value, bool)
+
+	// Convert brings a value from another domain, e.g. a stored variable,
+	// into this one
+	Convert(x Value) (Value, error)
+
+	// Bool represents a truth value; Truthy reports whether x counts as true
+	Bool(b bool) Value
+	Truthy(x Value) bool
+
+	Negate(x Value) (Value, error)
+
+	// Binary applies an arithmetic (+ - * / % ^) or comparison operator
+	Binary(ctx context.Context, op string, x, y Value) (Value, error)
+
+	// Factorial and DoubleFactorial implement x! and x!!
+	Factorial(ctx context.Context, x Value) (Value, error)
+	DoubleFactorial(ctx context.Context, x Value) (Value, error)
+
+	// Call applies a registered function. The argument count has already
+	// been checked against the function's signature.
+	Call(ctx context.Context, f Function, args []Value) (Value, error)
+}
+
+// SpecialValues is implemented by domains whose numbers are float64s and so
+// can be NaN or infinite, as IEEE-754 defines. The evaluator's "ieee" policy
+// only applies to these domains; the others have no NaN or infinity, so an
+// undefined or infinite result is always an error there.
+type SpecialValues interface {
+	// Special represents f, which is NaN or an infinity, in the domain
+	Special(f float64) Value
+}
+
+// DomainError is an argument outside an operation's domain, such as
+// sqrt(-1) or 1/0 with real numbers. Result is the value IEEE-754 gives
+// instead: NaN, or an infinity, as for log(0).
+type DomainError struct {
+	Msg    string
+	Result float64
+}
+
+func (e *DomainError) Error() string {
+	return e.Msg
+}
+
+// domainError returns a DomainError whose IEEE-754 result is result
+func domainError(result float64, format string, args ...any) error {
+	return &DomainError{Msg: fmt.Sprintf(format, args...), Result: result}
+}
+
+// FloatDomain evaluates with IEEE-754 float64 arithmetic. It is the default.
+// Like the hardware, it lets NaN and infinities through, so 1/0 is +Inf; the
+// evaluator decides whether such a result is an error.
+type FloatDomain struct{}
+
+func (FloatDomain) Name() string {
+	return "float"
+}
+
+func (FloatDomain) Literal(text string) (Value, error) {
+	x, err := strconv.ParseFloat(text, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, fmt.Errorf("number out of float64 range: %s", text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid number: %s", text)
+	}
+	return Float(x), nil
+}
+
+func (FloatDomain) Constant(name string) (Value, bool) {
+	switch name {
+	case "pi", "π":
+		return Float(Pi), true
+	case "e":
+		return Float(E), true
+	}
+	return nil, false
+}
+
+func (FloatDomain) Convert(x Value) (Value, error) {
+	return Float(x.Float64()), nil
+}
+
+func (FloatDomain) Bool(b bool) Value {
+	return Float(FromBool(b))
+}
+
+func (FloatDomain) Special(f float64) Value {
+	return Float(f)
+}
+
+func (FloatDomain) Truthy(x Value) bool {
+	return Truthy(x.Float64())
+}
+
+func (FloatDomain) Negate(x Value) (Value, error) {
+	return Float(-x.Float64()), nil
+}
+
+func (FloatDomain) Binary(ctx context.Context, op string, x, y Value) (Value, error) {
+	a, b := x.Float64(), y.Float64()
+
+	switch op {
+	case "+":
+		return Float(Add(a, b)), nil
+	case "-":
+		return Float(Subtract(a, b)), nil
+	case "*":
+		return Float(Multiply(a, b)), nil
+	case "/":
+		return Float(a / b), nil
+	case "%":
+		return Float(math.Mod(a, b)), nil
+	case "^":
+		return Float(Power(a, b)), nil
+	case "<", "<=", ">", ">=", "==", "!=":
+		result, err := Compare(op, a, b)
+		return Float(result), err
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+func (FloatDomain) Factorial(ctx context.Context, x Value) (Value, error) {
+	result, err := Factorial(x.Float64())
+	return Float(result), err
+}
+
+func (FloatDomain) DoubleFactorial(ctx context.Context, x Value) (Value, error) {
+	result, err := DoubleFactorial(x.Float64())
+	return Float(result), err
+}
+
+// Call keeps the exact result of an integer function as an Integer, since
+// float64 would round it
+func (FloatDomain) Call(ctx context.Context, f Function, args []Value) (Value, error) {
+	if v, ok, err := callInt(ctx, f, args); ok {
+		return v, err
+	}
+
+	result, err := f.Impl(Float64s(args))
+	if err != nil {
+		return nil, err
+	}
+	return Float(result), nil
+}
+
+// Float64s converts values to float64
+func Float64s(values []Value) []float64 {
+	floats := make([]float64, len(values))
+	for i, v := range values {
+		floats[i] = v.Float64()
+	}
+	return floats
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
 	"github.com/Oluwaseyi89/calculator-built-with-go/parser"
@@ -22,11 +29,23 @@ type CalculatorApp struct {
 }
 
 type Config struct {
-	AngleMode    string // "deg" or "rad"
-	Precision    int
-	Scientific   bool
-	ShowHistory  bool
-	ColorEnabled bool
+	AngleMode      string // "deg" or "rad"
+	NumberMode     string // "float", "big", "rational", "complex", "interval", "uncertain", "sigfig", "programmer" or "integer"
+	Base           int    // base of integer results: 2, 8, 10 or 16
+	Radix          int    // base from 2 to 36 of an extra line for real results; 10 for none
+	WordSize       int    // bits per word in programmer mode: 8, 16, 32 or 64, or 0 for any size
+	Signed         bool   // words in programmer mode hold negative numbers
+	Overflow       string // "error" or "promote": what integer mode does past OverflowBits
+	OverflowBits   int    // signed word size of integer mode's overflow errors, e.g. 64
+	Division       string // "trunc" or "floor": how integer mode rounds quotients
+	Precision      int    // significant digits in big mode
+	ComplexFormat  string // "rect" or "polar"
+	IntervalFormat string // "bounds" or "midrad"
+	SpecialValues  string // "strict" or "ieee"
+	Scientific     bool
+	ShowHistory    bool
+	ColorEnabled   bool
+	WarnAmbiguous  bool
 }
 
 func NewCalculatorApp() *CalculatorApp {
@@ -37,11 +56,21 @@ func NewCalculatorApp() *CalculatorApp {
 		scanner: bufio.NewScanner(os.Stdin),
 		history: make([]string, 0),
 		config: &Config{
-			AngleMode:    "rad",
-			Precision:    10,
-			Scientific:   false,
-			ShowHistory:  true,
-			ColorEnabled: true,
+			AngleMode:      "rad",
+			NumberMode:     "float",
+			Precision:      10,
+			Base:           10,
+			Radix:          10,
+			Signed:         true,
+			Overflow:       "error",
+			OverflowBits:   64,
+			Division:       "trunc",
+			ComplexFormat:  "rect",
+			IntervalFormat: "bounds",
+			SpecialValues:  "strict",
+			Scientific:     false,
+			ShowHistory:    true,
+			ColorEnabled:   true,
 		},
 	}
 }
@@ -71,11 +100,6 @@ func (app *CalculatorApp) mainLoop() {
 			continue
 		}
 
-		if err := utils.ValidateExpression(input); err != nil {
-			app.printError(fmt.Sprintf("Invalid expression: %v", err))
-			continue
-		}
-
 		app.evaluateExpression(input)
 	}
 }
@@ -99,6 +123,8 @@ func (app *CalculatorApp) handleCommand(input string) bool {
 		"examples":  app.showExamples,
 		"units":     app.showUnitConversions,
 		"stats":     app.showStatistics,
+		"funcs":     app.handleShowFunctions,
+		"inspect":   func() { app.handleInspect("") },
 	}
 
 	if handler, exists := commandHandlers[command]; exists {
@@ -116,11 +142,24 @@ func (app *CalculatorApp) handleSpecial(input string) bool {
 		// "export ":    app.handleExport,
 		// "import ":    app.handleImport,
 		"set ": app.handleSet,
-		// "del ":       app.handleDelete,
+		"def ": app.handleDefine,
+		"del ": app.handleDelete,
 		// "var ":       app.handleVariable,
 		"deg ":       app.handleDegrees,
 		"rad ":       app.handleRadians,
 		"precision ": app.handlePrecision,
+		"mode ":      app.handleMode,
+		"warn ":      app.handleWarn,
+		"complex ":   app.handleComplexFormat,
+		"interval ":  app.handleIntervalFormat,
+		"special ":   app.handleSpecialValues,
+		"base ":      app.handleBase,
+		"radix ":     app.handleRadix,
+		"inspect ":   app.handleInspect,
+		"word ":      app.handleWordSize,
+		"signed ":    app.handleSigned,
+		"overflow ":  app.handleOverflow,
+		"division ":  app.handleDivision,
 	}
 
 	for prefix, handler := range specialHandlers {
@@ -134,29 +173,88 @@ func (app *CalculatorApp) handleSpecial(input string) bool {
 	return false
 }
 
-func (app *CalculatorApp) evaluateExpression(expr string) {
+// evaluateExpression evaluates and displays expr, reporting whether it succeeded
+func (app *CalculatorApp) evaluateExpression(expr string) bool {
 	startTime := time.Now()
 
 	// Set angle mode in parser
 	app.parser.SetAngleMode(app.config.AngleMode)
 
 	// Evaluate expression
-	result, err := app.parser.EvaluateExpression(expr)
+	result, err := app.parser.EvaluateValue(context.Background(), expr)
 
 	duration := time.Since(startTime)
 
 	if err != nil {
-		app.printError(fmt.Sprintf("Evaluation error: %v", err))
-		return
+		app.printExprError(expr, err)
+		return false
 	}
 
 	app.displayResult(expr, result, duration)
+	app.printWarnings()
 
 	app.addToCommandHistory(expr)
+	return true
+}
+
+// formatValue renders a result for display. Exact integers are shown in the
+// selected base. Floats, complex numbers and intervals keep the
+// fixed-decimal format, and uncertain values are rounded to their
+// uncertainty; other values, such as the exact integers from nCr or
+// measurements in sig-fig mode, show the digits they carry.
+func (app *CalculatorApp) formatValue(value calculator.Value) string {
+	if n, ok := value.(calculator.Integer); ok && app.config.Base != 10 {
+		return utils.FormatInteger(app.bitPattern(n), app.config.Base)
+	}
+
+	switch v := value.(type) {
+	case calculator.Float:
+		return utils.FormatNumber(float64(v))
+	case calculator.Complex:
+		if v.IsReal() {
+			return utils.FormatNumber(real(v))
+		}
+		if app.config.ComplexFormat == "polar" {
+			return app.formatPolar(v)
+		}
+		return app.formatRectangular(v)
+	case calculator.Interval:
+		if v.IsPoint() {
+			return utils.FormatNumber(v.Lo)
+		}
+		return utils.FormatInterval(v.Lo, v.Hi, app.config.IntervalFormat == "midrad")
+	case calculator.Uncertain:
+		return utils.FormatUncertain(v.X, v.Sigma())
+	}
+	return value.String()
+}
+
+// formatRectangular renders z as a + bi. A part that is negligible next to
+// the other, such as the rounding error in e^(iπ), is shown as zero.
+func (app *CalculatorApp) formatRectangular(z calculator.Complex) string {
+	re, im := real(z), imag(z)
+	scale := cmplx.Abs(complex128(z))
+	if math.Abs(re) < 1e-15*scale {
+		re = 0
+	}
+	if math.Abs(im) < 1e-15*scale {
+		im = 0
+	}
+	return utils.FormatComplex(re, im)
+}
+
+// formatPolar renders z as r ∠ θ, with θ in the current angle mode
+func (app *CalculatorApp) formatPolar(z calculator.Complex) string {
+	r, theta := cmplx.Polar(complex128(z))
+	angle := utils.FormatNumber(theta)
+	if app.config.AngleMode == "deg" {
+		angle = utils.FormatNumber(utils.RadiansToDegrees(theta)) + "°"
+	}
+	return fmt.Sprintf("%s ∠ %s", utils.FormatNumber(r), angle)
 }
 
-func (app *CalculatorApp) displayResult(expr string, result float64, duration time.Duration) {
-	formattedResult := utils.FormatNumber(result)
+func (app *CalculatorApp) displayResult(expr string, value calculator.Value, duration time.Duration) {
+	formattedResult := app.formatValue(value)
 
 	if app.config.ColorEnabled {
 		app.printColorizedResult(expr, formattedResult, duration)
@@ -167,6 +265,61 @@ func (app *CalculatorApp) displayResult(expr string, result float64, duration ti
 	if duration > 10*time.Millisecond {
 		fmt.Printf("  (calculated in %s)\n", utils.FormatDuration(duration))
 	}
+	if x, ok := value.(calculator.RadixNumber); ok {
+		fmt.Printf("  Decimal: %s\n", utils.FormatNumber(x.Float64()))
+		return
+	}
+	app.displayRadix(value)
+
+	if r, ok := value.(calculator.Rational); ok {
+		app.displayRational(r)
+		return
+	}
+	if n, ok := value.(calculator.Integer); ok {
+		if app.config.NumberMode == "programmer" {
+			app.displayBases(n)
+		}
+		app.displayInteger(n)
+		return
+	}
+	if x, ok := value.(calculator.Interval); ok {
+		app.displayInterval(x)
+		return
+	}
+	if x, ok := value.(calculator.SigFig); ok {
+		app.displaySigFig(x)
+		return
+	}
+	if u, ok := value.(calculator.Uncertain); ok {
+		if !u.IsExact() {
+			app.displayUncertain(u)
+			return
+		}
+		value = calculator.Float(u.X)
+	}
+	if z, ok := value.(calculator.Complex); ok {
+		if !z.IsReal() {
+			app.displayComplex(z)
+			return
+		}
+		value = calculator.Float(real(z))
+	} else if app.config.NumberMode == "big" || app.config.NumberMode == "rational" {
+		// The fraction and integer lines would pass the approximation off
+		// as an exact result
+		if _, isFloat := value.(calculator.Float); isFloat {
+			fmt.Println("  (approximate: computed in float64 precision)")
+			return
+		}
+	}
+
+	f, isFloat := value.(calculator.Float)
+	if !isFloat {
+		return
+	}
+	result := float64(f)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return
+	}
 
 	// Show additional formats
 	if app.config.Scientific {
@@ -184,6 +337,171 @@ func (app *CalculatorApp) displayResult(expr string, result float64, duration ti
 	}
 }
 
+// displayIEEE shows how a result is stored as a float64 and as a float32.
+// Exact and big results are rounded once from the number they stand for,
+// since rounding a float64 to a float32, or a big.Float of 10 digits to a
+// float64, can land on the wrong neighbour.
+func (app *CalculatorApp) displayIEEE(value calculator.Value) {
+	x, x32 := value.Float64(), float32(value.Float64())
+	switch value.(type) {
+	case calculator.Integer, calculator.Rational, calculator.BigFloat, calculator.RadixNumber:
+		if r, err := calculator.ExactRat(value); err == nil {
+			x, _ = r.Float64()
+			x32, _ = r.Float32()
+		}
+	}
+	app.displayFloatInfo(calculator.InspectFloat64(x))
+	app.displayFloatInfo(calculator.InspectFloat32(x32))
+}
+
+// displayFloatInfo shows the bit fields of a float, what they encode, its
+// exact value and the gaps to its neighbours
+func (app *CalculatorApp) displayFloatInfo(info calculator.FloatInfo) {
+	fmt.Printf("  Float%d: %s\n", info.Width, info.Pattern())
+
+	sign := "+"
+	if info.Sign == 1 {
+		sign = "-"
+	}
+	switch class := info.Class(); class {
+	case "nan":
+		kind := "signalling"
+		if info.Quiet() {
+			kind = "quiet"
+		}
+		fmt.Printf("    Class: %s nan, payload 0x%X\n", kind, info.Payload())
+		return
+	case "infinity":
+		fmt.Printf("    Class: infinity, sign %s\n", sign)
+		return
+	default:
+		fmt.Printf("    Class: %s, sign %s, exponent 2^%d (stored %d), mantissa 0x%X\n", class, sign, info.Power(), info.Exponent, info.Mantissa)
+	}
+
+	format := func(x float64) string {
+		return strconv.FormatFloat(x, 'g', -1, info.Width)
+	}
+	fmt.Printf("    Exact: %s\n", info.Exact())
+	fmt.Printf("    Next down: %s (%+g)\n", format(info.Below), info.Below-info.Value)
+	fmt.Printf("    Next up: %s (%+g)\n", format(info.Above), info.Above-info.Value)
+}
+
+// displayComplex shows the form of a complex result not used for the main line
+func (app *CalculatorApp) displayComplex(z calculator.Complex) {
+	if app.config.ComplexFormat == "polar" {
+		fmt.Printf("  Rectangular: %s\n", app.formatRectangular(z))
+	} else {
+		fmt.Printf("  Polar: %s\n", app.formatPolar(z))
+	}
+}
+
+// displayInterval shows the form of an interval not used for the main line
+func (app *CalculatorApp) displayInterval(x calculator.Interval) {
+	if x.IsPoint() || math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) {
+		return
+	}
+	if app.config.IntervalFormat == "midrad" {
+		fmt.Printf("  Bounds: %s\n", utils.FormatInterval(x.Lo, x.Hi, false))
+	} else {
+		fmt.Printf("  Midpoint: %s\n", utils.FormatInterval(x.Lo, x.Hi, true))
+	}
+}
+
+// displayUncertain shows the relative uncertainty and the unrounded value
+func (app *CalculatorApp) displayUncertain(u calculator.Uncertain) {
+	sigma := u.Sigma()
+	if u.X != 0 {
+		fmt.Printf("  Relative: %.2g%%\n", 100*sigma/math.Abs(u.X))
+	}
+	fmt.Printf("  Unrounded: %s ± %s\n", utils.FormatNumber(u.X), utils.FormatNumber(sigma))
+}
+
+// displaySigFig shows how many significant figures a result carries and
+// its value before rounding
+func (app *CalculatorApp) displaySigFig(x calculator.SigFig) {
+	if math.IsNaN(x.X) || math.IsInf(x.X, 0) {
+		return
+	}
+	if x.IsExact() {
+		fmt.Println("  Significant figures: exact")
+		return
+	}
+	fmt.Printf("  Significant figures: %d\n", x.Figures)
+	fmt.Printf("  Unrounded: %s\n", strconv.FormatFloat(x.X, 'g', 15, 64))
+}
+
+// displayInteger shows the size of a long exact integer, and its
+// scientific form if enabled
+func (app *CalculatorApp) displayInteger(n calculator.Integer) {
+	digits := len(strings.TrimPrefix(n.String(), "-"))
+	if digits <= 15 {
+		return
+	}
+	fmt.Printf("  Digits: %d\n", digits)
+	if app.config.Scientific {
+		fmt.Printf("  Scientific: %.6e\n", n.Float64())
+	}
+}
+
+// bitPattern returns the bits of n in a programmer-mode word, so that -1 is
+// shown as 0xFF in 8-bit words. Without a word size, or outside programmer
+// mode, it returns n.
+func (app *CalculatorApp) bitPattern(n calculator.Integer) *big.Int {
+	if app.config.NumberMode != "programmer" {
+		return n.Int()
+	}
+	return app.programmerDomain().BitPattern(n.Int())
+}
+
+// displayBases shows an integer in each of the bases not used for the main
+// line, as a programmer's calculator does. Hex, octal and binary show the
+// bits of the word, and decimal shows its value.
+func (app *CalculatorApp) displayBases(n calculator.Integer) {
+	bases := []struct {
+		name string
+		base int
+	}{
+		{"Dec", 10},
+		{"Hex", 16},
+		{"Oct", 8},
+		{"Bin", 2},
+	}
+	for _, b := range bases {
+		if b.base == app.config.Base {
+			continue
+		}
+		digits := app.bitPattern(n)
+		if b.base == 10 {
+			digits = n.Int()
+		}
+		fmt.Printf("  %s: %s\n", b.name, utils.FormatInteger(digits, b.base))
+	}
+}
+
+// displayRadix shows a real result in the base chosen with radix, with
+// repeating fractional digits in parentheses
+func (app *CalculatorApp) displayRadix(value calculator.Value) {
+	if app.config.Radix == 10 {
+		return
+	}
+	r, err := calculator.ExactRat(value)
+	if err != nil {
+		return
+	}
+	fmt.Printf("  Base %d: %s\n", app.config.Radix, calculator.FormatRadix(r, app.config.Radix))
+}
+
+// displayRational shows an exact fraction as a mixed number and a decimal
+func (app *CalculatorApp) displayRational(r calculator.Rational) {
+	if r.Rat().IsInt() {
+		return
+	}
+	if mixed := r.Mixed(); mixed != r.String() {
+		fmt.Printf("  Mixed: %s\n", mixed)
+	}
+	fmt.Printf("  Decimal: %s\n", utils.FormatNumber(r.Float64()))
+}
+
 func (app *CalculatorApp) printColorizedResult(expr, formattedResult string, duration time.Duration) {
 	// ANSI color codes
 	const (
@@ -224,6 +542,43 @@ func (app *CalculatorApp) printError(msg string) {
 	}
 }
 
+// printExprError shows err, underlining the offending part of expr when the
+// error carries a position
+func (app *CalculatorApp) printExprError(expr string, err error) {
+	var perr *parser.Error
+	if !errors.As(err, &perr) {
+		app.printError(err.Error())
+		return
+	}
+
+	msg := perr.Message()
+
+	// Columns are counted in characters so that multi-byte input such as π lines up
+	col := utf8.RuneCountInString(expr[:perr.Pos])
+	width := utf8.RuneCountInString(expr[perr.Pos:perr.End])
+	if width < 1 {
+		width = 1
+	}
+	marker := strings.Repeat(" ", col) + "^" + strings.Repeat("~", width-1)
+
+	if app.config.ColorEnabled {
+		fmt.Printf("  %s\n  \033[1;31m%s\033[0m\n", expr, marker)
+	} else {
+		fmt.Printf("  %s\n  %s\n", expr, marker)
+	}
+	app.printError(msg)
+}
+
+func (app *CalculatorApp) printWarnings() {
+	for _, w := range app.parser.Warnings() {
+		if app.config.ColorEnabled {
+			fmt.Printf("\033[1;33mWarning:\033[0m %s\n", w)
+		} else {
+			fmt.Printf("Warning: %s\n", w)
+		}
+	}
+}
+
 func (app *CalculatorApp) printSuccess(msg string) {
 	if app.config.ColorEnabled {
 		fmt.Printf("\033[1;32m%s\033[0m\n", msg)
@@ -269,11 +624,24 @@ func (app *CalculatorApp) handleClearHistory() {
 func (app *CalculatorApp) handleSettings() {
 	app.printInfo("Current Settings:")
 	fmt.Printf("  Angle mode: %s\n", app.config.AngleMode)
-	fmt.Printf("  Precision: %d digits\n", app.config.Precision)
+	fmt.Printf("  Number mode: %s\n", app.config.NumberMode)
+	fmt.Printf("  Integer base: %d\n", app.config.Base)
+	fmt.Printf("  Radix: %d\n", app.config.Radix)
+	word := "any size"
+	if app.config.WordSize > 0 {
+		word = fmt.Sprintf("%d-bit", app.config.WordSize)
+	}
+	fmt.Printf("  Word size: %s, signed: %v\n", word, app.config.Signed)
+	fmt.Printf("  Integer overflow: %s, division: %s\n", app.overflowPolicy(), app.config.Division)
+	fmt.Printf("  Precision: %d significant digits in big mode\n", app.config.Precision)
+	fmt.Printf("  Complex format: %s\n", app.config.ComplexFormat)
+	fmt.Printf("  Interval format: %s\n", app.config.IntervalFormat)
+	fmt.Printf("  Special values: %s\n", app.config.SpecialValues)
 	fmt.Printf("  Scientific mode: %v\n", app.config.Scientific)
 	fmt.Printf("  Show history: %v\n", app.config.ShowHistory)
 	fmt.Printf("  Color output: %v\n", app.config.ColorEnabled)
-	fmt.Println("\nCommands: mode deg/rad, precision N, scientific on/off")
+	fmt.Printf("  Ambiguity warnings: %v\n", app.config.WarnAmbiguous)
+	fmt.Println("\nCommands: mode deg/rad, mode float/big/rational/complex/interval/uncertain/sigfig/programmer/integer, base 2/8/10/16, radix N, word 8/16/32/64/big, signed on/off, overflow error [bits]/promote, division trunc/floor, precision N, complex rect/polar, interval bounds/midrad, special strict/ieee, scientific on/off, warn on/off")
 }
 
 func (app *CalculatorApp) handleModeToggle() {
@@ -309,7 +677,20 @@ func (app *CalculatorApp) handleSave(filename string) {
 		filename = "calculator_state.json"
 	}
 	app.printInfo(fmt.Sprintf("Saving state to %s...", filename))
-	// Implementation for saving state
+
+	state := app.parser.State()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		app.printError(fmt.Sprintf("Failed to encode state: %v", err))
+		return
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		app.printError(fmt.Sprintf("Failed to save state: %v", err))
+		return
+	}
+
+	app.printSuccess(fmt.Sprintf("Saved %d variable(s) and %d function(s)", state.NumVariables(), len(state.Functions)))
 }
 
 func (app *CalculatorApp) handleLoad(filename string) {
@@ -317,7 +698,25 @@ func (app *CalculatorApp) handleLoad(filename string) {
 		filename = "calculator_state.json"
 	}
 	app.printInfo(fmt.Sprintf("Loading state from %s...", filename))
-	// Implementation for loading state
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		app.printError(fmt.Sprintf("Failed to load state: %v", err))
+		return
+	}
+
+	var state parser.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		app.printError(fmt.Sprintf("Invalid state file: %v", err))
+		return
+	}
+
+	if err := app.parser.Restore(state); err != nil {
+		app.printError(fmt.Sprintf("Invalid state file: %v", err))
+		return
+	}
+
+	app.printSuccess(fmt.Sprintf("Loaded %d variable(s) and %d function(s)", state.NumVariables(), len(state.Functions)))
 }
 
 func (app *CalculatorApp) handleSet(arg string) {
@@ -330,34 +729,79 @@ func (app *CalculatorApp) handleSet(arg string) {
 	variable := strings.TrimSpace(parts[0])
 	valueStr := strings.TrimSpace(parts[1])
 
-	if !utils.IsValidVariableName(variable) {
+	if !calculator.IsValidVariableName(variable) {
 		app.printError("Invalid variable name")
 		return
 	}
 
 	// Evaluate the value using parser
-	result, err := app.parser.EvaluateExpression(valueStr)
+	result, err := app.parser.EvaluateValue(context.Background(), valueStr)
 	if err != nil {
-		app.printError(fmt.Sprintf("Invalid value: %v", err))
+		app.printExprError(valueStr, err)
 		return
 	}
 
-	// Store variable in parser
-	err = app.parser.SetVariable(variable, result)
+	// Store variable in parser, keeping its full precision
+	err = app.parser.SetValue(variable, result)
 	if err != nil {
 		app.printError(fmt.Sprintf("Failed to set variable: %v", err))
 		return
 	}
 
-	app.printSuccess(fmt.Sprintf("Set %s = %s", variable, utils.FormatNumber(result)))
+	app.printSuccess(fmt.Sprintf("Set %s = %s", variable, app.formatValue(result)))
+}
+
+func (app *CalculatorApp) handleDefine(arg string) {
+	name, params, body, err := parser.ParseDefinition(arg)
+	if err != nil {
+		app.printError(fmt.Sprintf("Invalid definition: %v", err))
+		return
+	}
+
+	if err := app.parser.DefineFunction(name, params, body); err != nil {
+		var perr *parser.Error
+		if errors.As(err, &perr) {
+			app.printExprError(body, err)
+		} else {
+			app.printError(fmt.Sprintf("Invalid definition: %v", err))
+		}
+		return
+	}
+
+	fn, _ := app.parser.GetFunction(name)
+	app.printSuccess(fmt.Sprintf("Defined %s", fn))
+}
+
+func (app *CalculatorApp) handleDelete(name string) {
+	switch {
+	case app.parser.DeleteFunction(name):
+		app.printSuccess(fmt.Sprintf("Deleted function %s", name))
+	case app.parser.DeleteVariable(name):
+		app.printSuccess(fmt.Sprintf("Deleted variable %s", name))
+	default:
+		app.printError(fmt.Sprintf("No variable or function named %s", name))
+	}
+}
+
+func (app *CalculatorApp) handleShowFunctions() {
+	funcs := app.parser.Functions()
+	if len(funcs) == 0 {
+		fmt.Println("No user-defined functions")
+		return
+	}
+
+	app.printInfo("User-defined functions:")
+	for _, fn := range funcs {
+		fmt.Printf("  %s\n", fn)
+	}
 }
 
 func (app *CalculatorApp) handleDegrees(expr string) {
 	// Temporarily set angle mode to degrees
 	app.parser.SetAngleMode("deg")
-	result, err := app.parser.EvaluateExpression(expr)
+	result, err := app.parser.EvaluateValue(context.Background(), expr)
 	if err != nil {
-		app.printError(fmt.Sprintf("Error: %v", err))
+		app.printExprError(expr, err)
 		app.parser.SetAngleMode(app.config.AngleMode) // Restore original mode
 		return
 	}
@@ -368,9 +812,9 @@ func (app *CalculatorApp) handleDegrees(expr string) {
 func (app *CalculatorApp) handleRadians(expr string) {
 	// Temporarily set angle mode to radians
 	app.parser.SetAngleMode("rad")
-	result, err := app.parser.EvaluateExpression(expr)
+	result, err := app.parser.EvaluateValue(context.Background(), expr)
 	if err != nil {
-		app.printError(fmt.Sprintf("Error: %v", err))
+		app.printExprError(expr, err)
 		app.parser.SetAngleMode(app.config.AngleMode) // Restore original mode
 		return
 	}
@@ -378,14 +822,339 @@ func (app *CalculatorApp) handleRadians(expr string) {
 	app.displayResult(fmt.Sprintf("rad(%s)", expr), result, 0)
 }
 
+// handlePrecision sets the number of significant digits of big mode. The
+// other modes have a fixed precision, so it is refused there rather than
+// accepted and ignored.
 func (app *CalculatorApp) handlePrecision(arg string) {
+	if app.config.NumberMode != "big" {
+		app.printError(fmt.Sprintf("Precision only applies to big mode; %s mode has a fixed precision (use 'mode big' first)", app.config.NumberMode))
+		return
+	}
+
 	precision, err := strconv.Atoi(arg)
-	if err != nil || precision < 1 || precision > 20 {
-		app.printError("Precision must be between 1 and 20")
+	if err != nil || precision < 1 || precision > calculator.MaxBigDigits {
+		app.printError(fmt.Sprintf("Precision must be between 1 and %d", calculator.MaxBigDigits))
 		return
 	}
+
 	app.config.Precision = precision
-	app.printSuccess(fmt.Sprintf("Precision set to %d digits", precision))
+	app.applyNumberMode()
+	app.printSuccess(fmt.Sprintf("Precision set to %d significant digits", precision))
+	app.saveConfig()
+}
+
+// handleMode switches the angle mode (deg, rad) or the number mode (float,
+// big, rational, complex, interval, uncertain, sigfig, programmer, integer)
+func (app *CalculatorApp) handleMode(arg string) {
+	switch mode := strings.ToLower(arg); mode {
+	case "deg", "rad":
+		app.config.AngleMode = mode
+		app.printSuccess(fmt.Sprintf("Angle mode set to %s", mode))
+	case "float":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess("Using float64 arithmetic")
+	case "big":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess(fmt.Sprintf("Using arbitrary precision with %d significant digits (change with precision N)", app.config.Precision))
+	case "rational":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess("Using exact rational arithmetic")
+	case "complex":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess("Using complex arithmetic (i is the imaginary unit)")
+	case "interval":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess("Using interval arithmetic (write ranges as [lo, hi])")
+	case "uncertain":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess("Propagating uncertainties (write measurements as 5.03 ± 0.02 or 5.03 +/- 0.02)")
+	case "sigfig":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess("Tracking significant figures (2.50 has 3, 100 has 1, 100. has 3)")
+	case "programmer":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess("Using exact integers (write 0xFF, 0o17 or 0b1010; division truncates)")
+	case "integer":
+		app.config.NumberMode = mode
+		app.applyNumberMode()
+		app.printSuccess(fmt.Sprintf("Using exact integers (overflow: %s, division: %s)", app.overflowPolicy(), app.config.Division))
+	default:
+		app.printError("Usage: mode deg|rad|float|big|rational|complex|interval|uncertain|sigfig|programmer|integer")
+		return
+	}
+	if app.config.SpecialValues == "ieee" && !app.hasSpecialValues() {
+		app.printInfo(fmt.Sprintf("%s mode has no NaN or infinity, so such results are errors here", app.config.NumberMode))
+	}
+	app.saveConfig()
+}
+
+// hasSpecialValues reports whether the current mode can follow the IEEE
+// policy, that is whether its numbers can be NaN or infinite
+func (app *CalculatorApp) hasSpecialValues() bool {
+	_, ok := app.parser.Domain().(calculator.SpecialValues)
+	return ok
+}
+
+// applyNumberMode gives the parser the domain selected by the config
+func (app *CalculatorApp) applyNumberMode() {
+	switch app.config.NumberMode {
+	case "big":
+		app.parser.SetDomain(calculator.NewBigDomain(app.config.Precision))
+	case "rational":
+		app.parser.SetDomain(calculator.RatDomain{})
+	case "complex":
+		app.parser.SetDomain(calculator.ComplexDomain{})
+	case "interval":
+		app.parser.SetDomain(calculator.IntervalDomain{})
+	case "uncertain":
+		app.parser.SetDomain(calculator.UncertainDomain{})
+	case "sigfig":
+		app.parser.SetDomain(calculator.SigFigDomain{})
+	case "programmer":
+		app.parser.SetDomain(app.programmerDomain())
+	case "integer":
+		app.parser.SetDomain(app.integerDomain())
+	default:
+		app.parser.SetDomain(calculator.FloatDomain{})
+	}
+}
+
+// programmerDomain returns the domain of programmer mode with the
+// configured word
+func (app *CalculatorApp) programmerDomain() calculator.ProgrammerDomain {
+	return calculator.ProgrammerDomain{Bits: app.config.WordSize, Unsigned: !app.config.Signed}
+}
+
+// integerDomain returns the domain of integer mode, which either fails
+// past the configured width or promotes to integers of any size
+func (app *CalculatorApp) integerDomain() calculator.IntegerDomain {
+	d := calculator.IntegerDomain{Floor: app.config.Division == "floor"}
+	if app.config.Overflow == "error" {
+		d.Bits = app.config.OverflowBits
+	}
+	return d
+}
+
+// overflowPolicy describes the overflow setting, e.g. "error at 64 bits"
+func (app *CalculatorApp) overflowPolicy() string {
+	if app.config.Overflow == "error" {
+		return fmt.Sprintf("error at %d bits", app.config.OverflowBits)
+	}
+	return app.config.Overflow
+}
+
+// handleComplexFormat chooses rectangular (a + bi) or polar (r ∠ θ) display
+// for complex results
+func (app *CalculatorApp) handleComplexFormat(arg string) {
+	switch format := strings.ToLower(arg); format {
+	case "rect", "polar":
+		app.config.ComplexFormat = format
+		app.printSuccess(fmt.Sprintf("Complex results shown in %s form", format))
+	default:
+		app.printError("Usage: complex rect|polar")
+		return
+	}
+	app.saveConfig()
+}
+
+// handleIntervalFormat chooses [lo, hi] or mid ± rad display for intervals
+func (app *CalculatorApp) handleIntervalFormat(arg string) {
+	switch format := strings.ToLower(arg); format {
+	case "bounds", "midrad":
+		app.config.IntervalFormat = format
+		shown := "[lo, hi]"
+		if format == "midrad" {
+			shown = "mid ± rad"
+		}
+		app.printSuccess("Intervals shown as " + shown)
+	default:
+		app.printError("Usage: interval bounds|midrad")
+		return
+	}
+	app.saveConfig()
+}
+
+// handleInspect evaluates an expression and shows how its result is stored
+// in IEEE-754 floating point
+func (app *CalculatorApp) handleInspect(arg string) {
+	if arg == "" {
+		app.printError("Usage: inspect expr")
+		return
+	}
+	startTime := time.Now()
+	app.parser.SetAngleMode(app.config.AngleMode)
+	result, err := app.parser.EvaluateValue(context.Background(), arg)
+	duration := time.Since(startTime)
+	if err != nil {
+		app.printExprError(arg, err)
+		return
+	}
+
+	app.displayResult(arg, result, duration)
+	app.displayIEEE(result)
+	app.printWarnings()
+	app.addToCommandHistory(arg)
+}
+
+// handleBase chooses the base integer results are shown in
+func (app *CalculatorApp) handleBase(arg string) {
+	switch base, _ := strconv.Atoi(arg); base {
+	case 2, 8, 10, 16:
+		app.config.Base = base
+		app.printSuccess(fmt.Sprintf("Integer results shown in base %d", base))
+	default:
+		app.printError("Usage: base 2|8|10|16")
+		return
+	}
+	app.saveConfig()
+}
+
+// handleRadix chooses the base, from 2 to 36, of an extra line shown for
+// every real result; radix 10 turns it off
+func (app *CalculatorApp) handleRadix(arg string) {
+	radix, err := strconv.Atoi(arg)
+	if err != nil || radix < 2 || radix > 36 {
+		app.printError("Usage: radix N (N from 2 to 36; 10 for none)")
+		return
+	}
+	app.config.Radix = radix
+	if radix == 10 {
+		app.printSuccess("Results shown in decimal only")
+	} else {
+		app.printSuccess(fmt.Sprintf("Results also shown in base %d", radix))
+	}
+	app.saveConfig()
+}
+
+// handleWordSize chooses the word size of programmer mode
+func (app *CalculatorApp) handleWordSize(arg string) {
+	switch arg = strings.ToLower(arg); arg {
+	case "8", "16", "32", "64":
+		app.config.WordSize, _ = strconv.Atoi(arg)
+		app.printSuccess(fmt.Sprintf("Programmer mode wraps results to %s-bit words", arg))
+	case "big":
+		app.config.WordSize = 0
+		app.printSuccess("Programmer mode uses integers of any size")
+	default:
+		app.printError("Usage: word 8|16|32|64|big")
+		return
+	}
+	app.applyNumberMode()
+	app.saveConfig()
+}
+
+// handleSigned chooses between signed and unsigned words in programmer mode
+func (app *CalculatorApp) handleSigned(arg string) {
+	switch strings.ToLower(arg) {
+	case "on":
+		app.config.Signed = true
+		app.printSuccess("Words are signed (two's complement)")
+	case "off":
+		app.config.Signed = false
+		app.printSuccess("Words are unsigned")
+	default:
+		app.printError("Usage: signed on|off")
+		return
+	}
+	app.applyNumberMode()
+	app.saveConfig()
+}
+
+// handleOverflow chooses whether integer mode fails on results that do not
+// fit in a signed word, 64 bits unless a width is given, or promotes them to
+// integers of any size
+func (app *CalculatorApp) handleOverflow(arg string) {
+	fields := strings.Fields(strings.ToLower(arg))
+	policy := ""
+	if len(fields) > 0 {
+		policy = fields[0]
+	}
+	switch {
+	case policy == "error" && len(fields) <= 2:
+		bits := 64
+		if len(fields) == 2 {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 2 {
+				app.printError("Overflow width must be a whole number of bits, at least 2")
+				return
+			}
+			bits = n
+		}
+		app.config.Overflow = policy
+		app.config.OverflowBits = bits
+		app.printSuccess(fmt.Sprintf("Integer results past %d bits are overflow errors", bits))
+	case policy == "promote" && len(fields) == 1:
+		app.config.Overflow = policy
+		app.printSuccess("Integer results grow to any size")
+	default:
+		app.printError("Usage: overflow error [bits]|promote")
+		return
+	}
+	app.applyNumberMode()
+	app.saveConfig()
+}
+
+// handleDivision chooses whether integer division truncates toward zero or
+// floors toward -infinity
+func (app *CalculatorApp) handleDivision(arg string) {
+	switch rounding := strings.ToLower(arg); rounding {
+	case "trunc":
+		app.config.Division = rounding
+		app.printSuccess("Integer division truncates toward zero: -7/2 = -3, -7 % 2 = -1")
+	case "floor":
+		app.config.Division = rounding
+		app.printSuccess("Integer division floors: -7/2 = -4, -7 % 2 = 1")
+	default:
+		app.printError("Usage: division trunc|floor")
+		return
+	}
+	app.applyNumberMode()
+	app.saveConfig()
+}
+
+// handleSpecialValues chooses whether NaN and infinite results are errors
+// or are shown as nan and inf
+func (app *CalculatorApp) handleSpecialValues(arg string) {
+	switch policy := strings.ToLower(arg); policy {
+	case "strict":
+		app.config.SpecialValues = policy
+		app.printSuccess("NaN and infinite results are errors")
+	case "ieee":
+		if !app.hasSpecialValues() {
+			app.printError(fmt.Sprintf("special ieee needs float, complex, uncertain or sigfig mode; %s mode has no NaN or infinity", app.config.NumberMode))
+			return
+		}
+		app.config.SpecialValues = policy
+		app.printSuccess("NaN and infinite results follow IEEE-754, e.g. 1/0 = inf")
+	default:
+		app.printError("Usage: special strict|ieee")
+		return
+	}
+	app.parser.SetSpecialValues(app.config.SpecialValues)
+	app.saveConfig()
+}
+
+func (app *CalculatorApp) handleWarn(arg string) {
+	switch strings.ToLower(arg) {
+	case "on":
+		app.config.WarnAmbiguous = true
+	case "off":
+		app.config.WarnAmbiguous = false
+	default:
+		app.printError("Usage: warn on|off")
+		return
+	}
+
+	app.parser.SetWarnAmbiguous(app.config.WarnAmbiguous)
+	app.printSuccess(fmt.Sprintf("Ambiguity warnings %s", strings.ToLower(arg)))
 	app.saveConfig()
 }
 
@@ -448,27 +1217,54 @@ func (app *CalculatorApp) showFullHelp() {
 			"EXPRESSION SYNTAX",
 			`  + - * / ^      - Basic arithmetic
   %              - Modulus/Percentage
-  !              - Factorial
+  ! !!           - Factorial, double factorial
   ( )            - Parentheses for grouping
+  [lo, hi]       - Interval (in interval mode)
+  x ± s, x +/- s - Value with an uncertainty (in uncertain mode)
+  2x, 3(x+1)     - Implicit multiplication
+  < <= > >= == != - Comparison (1 = true, 0 = false)
+  & | xor ~      - Bitwise and, or, xor, not (in programmer mode)
+  << >> >>>      - Shifts; >>> shifts in zeros (in programmer mode)
+  && || not      - Logical and, or, not
+  if(c, a, b)    - a if c is true, else b (lazy)
   pi, e          - Mathematical constants
   ans            - Previous result`,
 		},
 		{
 			"MATHEMATICAL FUNCTIONS",
-			`  sin(x), cos(x), tan(x)    - Trigonometric
-  asin(x), acos(x), atan(x) - Inverse trigonometric
-  sinh(x), cosh(x), tanh(x) - Hyperbolic
-  sqrt(x), cbrt(x)          - Square/cube root
-  log(x), log10(x)          - Natural/base-10 log
-  exp(x)                    - Exponential e^x
-  abs(x)                    - Absolute value`,
+			app.functionHelp(),
 		},
 		{
 			"ADVANCED COMMANDS",
 			`  set var=expr    - Set variable
+  def f(x)=expr  - Define a function
+  del name       - Delete a variable or function
+  funcs          - List user-defined functions
+  save/load FILE - Save or load variables and functions
   deg expr       - Evaluate in degrees mode
   rad expr       - Evaluate in radians mode
-  precision N    - Set display precision (1-20)
+  mode float/big - Use float64 or arbitrary-precision arithmetic
+  mode rational  - Use exact fractions, e.g. 1/3 + 1/6 = 1/2
+  mode complex   - Use complex numbers, e.g. sqrt(-4) = 2i
+  complex polar  - Show complex results as r ∠ θ (rect for a + bi)
+  mode interval  - Use intervals with rigorous bounds, e.g. [9.9, 10.1] * 2
+  interval midrad - Show intervals as mid ± rad (bounds for [lo, hi])
+  mode uncertain - Propagate uncertainties, e.g. (5.03 ± 0.02) * 2
+  mode sigfig    - Round results by significant figures, e.g. 2.50 * 3.1 = 7.8
+  mode programmer - Use exact integers with 0xFF, 0o17 and 0b1010 literals
+  base 2/8/10/16 - Show integer results in binary, octal, decimal or hex
+  radix N        - Also show results in base N from 2 to 36 (10 for none)
+  inspect expr   - Show the float64 and float32 bits of a result, e.g. inspect 0.1
+  tobase(x, n)   - Write x in base n, e.g. tobase(0.1, 2) = 0.0(0011)
+  frombase("zz", 36) - Read digits in base n, e.g. 1295
+  word 8/16/32/64 - Wrap programmer-mode results to a word (big for any size)
+  signed on/off  - Use signed (two's complement) or unsigned words
+  mode integer   - Use exact integers, e.g. 2^62 + 1 = 4611686018427387905
+  overflow promote - Let integers grow to any size (error N fails past N bits, 64 by default)
+  division floor - Floor integer division, -7/2 = -4 (trunc gives -3)
+  special ieee   - Give inf and nan, e.g. 1/0 = inf (strict makes them errors)
+  precision N    - Significant digits in big mode (up to 10000)
+  warn on/off    - Warn about ambiguous input like 1/2x
   examples       - Show usage examples
   units          - Show unit conversions
   stats          - Statistical functions help`,
@@ -481,7 +1277,9 @@ func (app *CalculatorApp) showFullHelp() {
   log(e^2)            = 2
   deg sin(90)         = 1
   set x = 5           (assign variable)
-  x^2 + 3*x + 2       (use variable)`,
+  x^2 + 3*x + 2       (use variable)
+  def f(x, y) = sqrt(x^2 + y^2)
+  f(3, 4)             = 5`,
 		},
 	}
 
@@ -491,6 +1289,15 @@ func (app *CalculatorApp) showFullHelp() {
 	}
 }
 
+// functionHelp lists every registered function with its documentation
+func (app *CalculatorApp) functionHelp() string {
+	var lines []string
+	for _, fn := range calculator.Functions() {
+		lines = append(lines, fmt.Sprintf("  %-25s - %s", fn.Signature(), fn.Doc))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (app *CalculatorApp) showExamples() {
 	app.printInfo("=== EXAMPLE EXPRESSIONS ===")
 
@@ -564,14 +1371,17 @@ func (app *CalculatorApp) handleCommandLineArgs(args []string) {
 	case "--interactive", "-i":
 		app.Run()
 	case "--eval", "-e":
-		if len(args) > 1 {
-			expr := strings.Join(args[1:], " ")
-			app.evaluateExpression(expr)
-		} else {
+		if len(args) < 2 {
 			fmt.Println("Error: No expression provided for --eval")
+			os.Exit(2)
+		}
+		expr := strings.Join(args[1:], " ")
+		if !app.evaluateExpression(expr) {
+			os.Exit(1)
 		}
 	default:
 		fmt.Printf("Unknown option: %s\n", args[0])
 		fmt.Println("Usage: calculator [--help|--version|--eval EXPR]")
+		os.Exit(2)
 	}
 }
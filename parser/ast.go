@@ -0,0 +1,95 @@
+package parser
+
+// Node is an element of a parsed expression tree. Pos and End report the
+// byte offsets in the original input where the node starts and ends.
+type Node interface {
+	Pos() int
+	End() int
+}
+
+// NumberLit is a numeric literal such as 42, 3.5, 1e-3 or 0xFF
+type NumberLit struct {
+	ValuePos int
+	Text     string
+	Value    float64
+}
+
+// StringLit is a string in double quotes such as "ff", which may only be
+// passed to functions that take strings
+type StringLit struct {
+	ValuePos int
+	Value    string
+}
+
+// Ident is a reference to a variable or constant
+type Ident struct {
+	NamePos int
+	Name    string
+}
+
+// UnaryExpr is a prefix operator applied to an operand, e.g. -x
+type UnaryExpr struct {
+	OpPos int
+	Op    string
+	X     Node
+}
+
+// BinaryExpr is an infix operator applied to two operands, e.g. a + b.
+// Implicit is set for multiplication written by juxtaposition, e.g. 2x.
+type BinaryExpr struct {
+	X        Node
+	OpPos    int
+	Op       string
+	Y        Node
+	Implicit bool
+}
+
+// PostfixExpr is a suffix operator applied to an operand, e.g. n! or n!!
+type PostfixExpr struct {
+	X     Node
+	OpPos int
+	Op    string
+}
+
+// ParenExpr is a parenthesised expression, kept so that error spans
+// include the parentheses
+type ParenExpr struct {
+	Lparen int
+	X      Node
+	Rparen int
+}
+
+// IntervalExpr is an interval literal such as [9.9, 10.1]
+type IntervalExpr struct {
+	Lbrack int
+	Lo, Hi Node
+	Rbrack int
+}
+
+// CallExpr is a function call such as max(a, b)
+type CallExpr struct {
+	NamePos int
+	Name    string
+	Args    []Node
+	Rparen  int
+}
+
+func (n *NumberLit) Pos() int    { return n.ValuePos }
+func (n *StringLit) Pos() int    { return n.ValuePos }
+func (n *Ident) Pos() int        { return n.NamePos }
+func (n *UnaryExpr) Pos() int    { return n.OpPos }
+func (n *BinaryExpr) Pos() int   { return n.X.Pos() }
+func (n *PostfixExpr) Pos() int  { return n.X.Pos() }
+func (n *ParenExpr) Pos() int    { return n.Lparen }
+func (n *IntervalExpr) Pos() int { return n.Lbrack }
+func (n *CallExpr) Pos() int     { return n.NamePos }
+
+func (n *NumberLit) End() int    { return n.ValuePos + len(n.Text) }
+func (n *StringLit) End() int    { return n.ValuePos + len(n.Value) + 2 }
+func (n *Ident) End() int        { return n.NamePos + len(n.Name) }
+func (n *UnaryExpr) End() int    { return n.X.End() }
+func (n *BinaryExpr) End() int   { return n.Y.End() }
+func (n *PostfixExpr) End() int  { return n.OpPos + len(n.Op) }
+func (n *ParenExpr) End() int    { return n.Rparen + 1 }
+func (n *IntervalExpr) End() int { return n.Rbrack + 1 }
+func (n *CallExpr) End() int     { return n.Rparen + 1 }
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// TestCancelLongBuiltins checks that builtins which run for seconds notice
+// a cancelled context themselves. The evaluator only checks the context
+// between steps, so each builtin is called directly with one that is
+// already cancelled; one that ignored it would run to the end instead.
+func TestCancelLongBuiltins(t *testing.T) {
+	cases := []struct {
+		Func   string
+		Args   []string
+		Domain calculator.Domain
+	}{
+		{"factor", []string{"(2^61-1)*(2^89-1)"}, calculator.RatDomain{}},
+		{"sin", []string{"exp(log(2))"}, calculator.NewBigDomain(10000)},
+		{"ncr", []string{"100000", "50000"}, calculator.FloatDomain{}},
+	}
+
+	for _, c := range cases {
+		f, ok := calculator.LookupFunction(c.Func)
+		if !ok {
+			t.Fatalf("%s is not registered", c.Func)
+		}
+		args := make([]calculator.Value, len(c.Args))
+		for i, expr := range c.Args {
+			prog, err := Compile(expr)
+			if err != nil {
+				t.Fatalf("%s: %v", expr, err)
+			}
+			if args[i], err = prog.EvalIn(context.Background(), c.Domain, nil); err != nil {
+				t.Fatalf("%s in %s mode: %v", expr, c.Domain.Name(), err)
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := c.Domain.Call(ctx, f, args); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s%v in %s mode: got error %v, want it cancelled", c.Func, c.Args, c.Domain.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// conformanceCase is an expression together with the value the operator
+// rules require it to produce
+type conformanceCase struct {
+	Expr string
+	Want float64
+	Rule string
+}
+
+// conformanceCases pin down operator precedence and associativity. Any
+// change to the grammar must keep every case passing.
+var conformanceCases = []conformanceCase{
+	// Left-associative arithmetic
+	{"10 - 4 - 3", 3, "- is left-associative"},
+	{"2 - 3 + 4", 3, "+ and - share a level"},
+	{"100 / 10 / 5", 2, "/ is left-associative"},
+	{"7 % 4 * 2", 6, "% and * share a level"},
+	{"1 + 2 * 3", 7, "* binds tighter than +"},
+
+	// Exponentiation
+	{"2^3^2", 512, "^ is right-associative"},
+	{"(2^3)^2", 64, "parentheses override associativity"},
+	{"2^2^3", 256, "^ is right-associative"},
+	{"2 * 3^2", 18, "^ binds tighter than *"},
+	{"2^-1", 0.5, "unary minus in an exponent"},
+	{"2^-1 * 4", 2, "exponent operand stops at *"},
+
+	// Unary minus
+	{"-2^2", -4, "^ binds tighter than unary minus"},
+	{"(-2)^2", 4, "parenthesised negative base"},
+	{"-2^-2", -0.25, "unary minus on both sides of ^"},
+	{"--2", 2, "repeated unary minus"},
+	{"-(2 + 3)", -5, "unary minus on a group"},
+	{"3 * -2", -6, "unary minus after an operator"},
+
+	// Factorial
+	{"2 + 3!", 8, "! binds tighter than +"},
+	{"2 * 3!", 12, "! binds tighter than *"},
+	{"2^3!", 64, "! binds tighter than ^"},
+	{"3!^2", 36, "! applies before ^"},
+	{"-3!", -6, "! binds tighter than unary minus"},
+	{"3!!", 3, "!! is the double factorial"},
+	{"(3!)!", 720, "! is repeated with parentheses"},
+	{"3! !", 720, "separated ! are repeated"},
+	{"0.5!", math.Sqrt(math.Pi) / 2, "! on a non-integer is gamma(x+1)"},
+	{"(2 + 1)!", 6, "! on a group"},
+	{"0!", 1, "0! is 1"},
+
+	// Implicit multiplication
+	{"2pi", 2 * math.Pi, "number followed by a constant"},
+	{"3(1 + 1)", 6, "number followed by a group"},
+	{"(2 + 1)(2 - 1)", 3, "adjacent groups"},
+	{"2sqrt(16)", 8, "number followed by a call"},
+	{"2(3)^2", 18, "^ binds tighter than implicit multiplication"},
+	{"2^3(2)", 16, "implicit multiplication after a power"},
+	{"1/2(4)", 2, "implicit multiplication shares the level of /"},
+	{"-2(3)", -6, "unary minus before implicit multiplication"},
+	{"2e", 2 * math.E, "e without exponent digits is the constant"},
+	{"2e2", 200, "e with exponent digits is part of the number"},
+
+	// Comparison and logic
+	{"1 < 2", 1, "true is 1"},
+	{"2 <= 1", 0, "false is 0"},
+	{"1 + 1 == 2", 1, "arithmetic binds tighter than =="},
+	{"1 < 2 == 2 < 3", 1, "relational binds tighter than equality"},
+	{"2 * 3 > 5", 1, "arithmetic binds tighter than >"},
+	{"3! == 6", 1, "factorial before comparison"},
+	{"1 || 0 && 0", 1, "&& binds tighter than ||"},
+	{"not 1 == 2", 1, "not binds looser than comparison"},
+	{"not 0 && 0", 0, "not binds tighter than &&"},
+	{"not not 5", 1, "repeated not"},
+	{"0 && 1/0", 0, "&& short-circuits"},
+	{"1 || 1/0", 1, "|| short-circuits"},
+	{"if(1, 2, 3)", 2, "if selects the second argument when true"},
+	{"if(0, 1/0, 3)", 3, "if does not evaluate the branch not taken"},
+	{"if(2 > 1, 10, 20) + 1", 11, "if is an ordinary operand"},
+}
+
+// bitwiseCases pin down the bitwise operators, which are evaluated in
+// programmer mode with signed 8-bit words
+var bitwiseCases = []conformanceCase{
+	// Precedence
+	{"6 | 3 & 5", 7, "& binds tighter than |"},
+	{"6 xor 3 & 5", 7, "& binds tighter than xor"},
+	{"1 | 6 xor 3", 5, "xor binds tighter than |"},
+	{"1 << 2 & 4", 4, "<< binds tighter than &"},
+	{"1 << 2 + 1", 8, "+ binds tighter than <<"},
+	{"16 >> 2 >> 1", 2, ">> is left-associative"},
+	{"12 & 10 == 8", 1, "& binds tighter than =="},
+	{"~1 + 1", -1, "~ binds tighter than +"},
+	{"~2^2", -5, "^ binds tighter than ~"},
+
+	// Two's complement words
+	{"~0", -1, "~ flips every bit"},
+	{"0x7F + 1", -128, "signed words wrap around"},
+	{"-16 >> 2", -4, ">> keeps the sign"},
+	{"-16 >>> 2", 60, ">>> shifts in zeros"},
+}
+
+// exactCase is an integer function call together with its exact result,
+// or with the error it must give instead of a rounded result
+type exactCase struct {
+	Expr   string
+	Domain calculator.Domain
+	Want   string
+	Err    string
+}
+
+// exactCases pin down integer functions on arguments near and above 2^53,
+// where float64 stops holding every integer, and integer mode's refusal to
+// round results that are not integers
+var exactCases = []exactCase{
+	{"isprime(2^61 - 1)", calculator.RatDomain{}, "1", ""},
+	{"totient(2^61 - 1)", calculator.RatDomain{}, "2305843009213693950", ""},
+	{"factor(2^200 + 1)", calculator.RatDomain{}, "257 * 1601 * 25601 * 82471201 * 4278255361 * 432363203127002885506543172618401", ""},
+	{"factor((2^61 - 1) * (2^89 - 1))", calculator.RatDomain{}, "", "could not fully factor"},
+	{"isprime(2^53 - 111)", calculator.FloatDomain{}, "1", ""},
+	{"isprime(2305843009213693951)", calculator.FloatDomain{}, "", "not exactly representable"},
+	{"totient(2^61 - 1)", calculator.FloatDomain{}, "", "not exactly representable"},
+	{"factor(2^200 + 1)", calculator.FloatDomain{}, "", "not exactly representable"},
+	{"lcm(2^60 + 1, 1)", calculator.RatDomain{}, "1152921504606846977", ""},
+	{"lcm(2^53, 1)", calculator.FloatDomain{}, "9007199254740992", ""},
+	{"lcm(2^60 + 1, 1)", calculator.FloatDomain{}, "", "not exactly representable"},
+	{"nCr(2^60, 2)", calculator.FloatDomain{}, "", "not exactly representable"},
+	{"nPr(2^60 + 1, 1)", calculator.FloatDomain{}, "", "not exactly representable"},
+	{"factor(2^64 + 1)", calculator.NewBigDomain(10), "", "not exactly representable at 10 digits"},
+	{"isprime(2^89 - 1)", calculator.NewBigDomain(10), "", "not exactly representable at 10 digits"},
+	{"factor(2^64 + 1)", calculator.NewBigDomain(30), "274177 * 67280421310721", ""},
+	{"isprime(2^89 - 1)", calculator.NewBigDomain(30), "1", ""},
+	{"sqrt(16) + 2^62", calculator.IntegerDomain{}, "4611686018427387908", ""},
+	{"abs(-(2^62 + 1))", calculator.IntegerDomain{}, "4611686018427387905", ""},
+	{"max(2^62 + 1, 3)", calculator.IntegerDomain{Bits: 64}, "4611686018427387905", ""},
+	{"(-1)^-3", calculator.IntegerDomain{}, "-1", ""},
+	{"sqrt(17)", calculator.IntegerDomain{}, "", "sqrt(17) is not an integer in integer mode"},
+	{"sin(1)", calculator.IntegerDomain{}, "", "sin(1) is not an integer"},
+	{"2^-1", calculator.IntegerDomain{}, "", "2^-1 is not an integer"},
+	{"(-2)^-1", calculator.IntegerDomain{Floor: true}, "", "(-2)^-1 is not an integer"},
+	{"sqrt(2^61)", calculator.IntegerDomain{}, "", "too large to compute exactly"},
+	{"exp(40)", calculator.IntegerDomain{}, "", "too large to compute exactly"},
+	{"sqrt(0x10)", calculator.ProgrammerDomain{Bits: 8}, "4", ""},
+	{"sqrt(17)", calculator.ProgrammerDomain{Bits: 8}, "", "sqrt(17) is not an integer in programmer mode"},
+	{"sin(1)", calculator.ProgrammerDomain{}, "", "sin(1) is not an integer"},
+	{"2^-1", calculator.ProgrammerDomain{Bits: 32}, "", "2^-1 is not an integer"},
+	{"pi", calculator.ProgrammerDomain{}, "", "pi is not available in programmer mode"},
+	{"2 * e", calculator.IntegerDomain{}, "", "e is not available in integer mode"},
+}
+
+func TestConformance(t *testing.T) {
+	checkConformance(t, conformanceCases, calculator.FloatDomain{})
+}
+
+func TestBitwiseConformance(t *testing.T) {
+	checkConformance(t, bitwiseCases, calculator.ProgrammerDomain{Bits: 8})
+}
+
+// TestNegativeShift checks that a negative shift count is an error, as in
+// Go, including in unsigned words, where -1 wraps to the largest word
+func TestNegativeShift(t *testing.T) {
+	domains := []calculator.ProgrammerDomain{{Bits: 8}, {Bits: 8, Unsigned: true}, {Bits: 64, Unsigned: true}, {}}
+	for _, domain := range domains {
+		for _, expr := range []string{"1 >> -1", "1 << -1", "1 >>> -1", "1 >> (2 - 3)"} {
+			prog, err := Compile(expr)
+			if err != nil {
+				t.Fatalf("%s: %v", expr, err)
+			}
+			result, err := prog.EvalIn(context.Background(), domain, nil)
+			if err == nil || !strings.Contains(err.Error(), "negative shift count") {
+				t.Errorf("%s in %+v = %v, %v, want a negative shift count error", expr, domain, result, err)
+			}
+		}
+	}
+}
+
+// TestRadixPrefixTypos checks that a radix prefix followed by a character
+// outside its base is reported at the literal in programmer mode, rather
+// than read as 0 times a variable
+func TestRadixPrefixTypos(t *testing.T) {
+	cases := map[string]string{
+		"0xG":        "invalid hex literal: 0xG",
+		"1 + 0b2":    "invalid binary literal: 0b2",
+		"0o9^2":      "invalid octal literal: 0o9",
+		"-0xg(1)":    "invalid hex literal: 0xg",
+		"0b102":      "invalid binary literal: 0b102",
+		"2 * 0xffz":  "invalid hex literal: 0xffz",
+		"3 & 0xQQ":   "invalid hex literal: 0xQQ",
+		"0b1 + 0o78": "invalid octal literal: 0o78",
+	}
+	domain := calculator.ProgrammerDomain{}
+	one, err := domain.Literal("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Variables with these names must not change the error
+	vars := map[string]calculator.Value{"xG": one, "b2": one, "o9": one}
+	for expr, want := range cases {
+		prog, err := Compile(expr)
+		if err == nil {
+			_, err = prog.EvalIn(context.Background(), domain, vars)
+		}
+		var perr *Error
+		if !errors.As(err, &perr) || perr.Msg != want {
+			t.Errorf("%s: got error %v, want %q", expr, err, want)
+			continue
+		}
+		if lit := expr[perr.Pos:perr.End]; lit != want[strings.LastIndex(want, " ")+1:] {
+			t.Errorf("%s: error points at %q, want the literal", expr, lit)
+		}
+	}
+}
+
+// TestBigResultSize checks that big mode refuses results too large to
+// print, which it can compute at once, rather than hang when showing them
+func TestBigResultSize(t *testing.T) {
+	domain := calculator.NewBigDomain(10)
+	cases := map[string]string{
+		"2^(10^9)":            "result too large for big mode",
+		"exp(10^8)":           "result too large for big mode",
+		"2^-(10^9)":           "result too small for big mode",
+		"1e1000000":           "result too large for big mode",
+		"2^(10^6) / 2^(10^6)": "",
+	}
+	for expr, want := range cases {
+		prog, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		result, err := prog.EvalIn(context.Background(), domain, nil)
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("%s in big mode: %v", expr, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("%s in big mode = %v, %v, want an error containing %q", expr, result, err, want)
+		}
+	}
+}
+
+// TestApproximateRationals checks that a float64 result in rational mode
+// stays approximate, even when it is a whole number, instead of being
+// passed off as exact in later arithmetic
+func TestApproximateRationals(t *testing.T) {
+	for _, expr := range []string{"sqrt(10^20 + 1) - 10^10", "floor(pi) + 1/2", "-floor(pi)"} {
+		prog, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		result, err := prog.EvalIn(context.Background(), calculator.RatDomain{}, nil)
+		if err != nil {
+			t.Errorf("%s in rational mode: %v", expr, err)
+			continue
+		}
+		if _, ok := result.(calculator.Float); !ok {
+			t.Errorf("%s in rational mode = %T %s, want an approximate Float", expr, result, result)
+		}
+	}
+}
+
+// TestToBaseDigits checks that tobase shows a float's stored bits when it
+// does not stand for a simple fraction
+func TestToBaseDigits(t *testing.T) {
+	cases := map[string]string{
+		"tobase(2^-30, 2)":   "0." + strings.Repeat("0", 29) + "1",
+		"tobase(sqrt(2), 2)": "1.0110101000001001111001100110011111110011101111001101",
+		"tobase(0.1, 2)":     "0.0(0011)",
+		"tobase(1/3, 3)":     "0.1",
+	}
+	for expr, want := range cases {
+		prog, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		result, err := prog.EvalIn(context.Background(), calculator.FloatDomain{}, nil)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+		} else if result.String() != want {
+			t.Errorf("%s = %s, want %s", expr, result, want)
+		}
+	}
+}
+
+func TestExactIntegers(t *testing.T) {
+	for _, c := range exactCases {
+		prog, err := Compile(c.Expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.Expr, err)
+			continue
+		}
+
+		result, err := prog.EvalIn(context.Background(), c.Domain, nil)
+		switch {
+		case c.Err != "":
+			if err == nil || !strings.Contains(err.Error(), c.Err) {
+				t.Errorf("%s in %s mode: got %v, %v, want an error containing %q", c.Expr, c.Domain.Name(), result, err, c.Err)
+			}
+		case err != nil:
+			t.Errorf("%s in %s mode: %v", c.Expr, c.Domain.Name(), err)
+		case result.String() != c.Want:
+			t.Errorf("%s in %s mode = %s, want %s", c.Expr, c.Domain.Name(), result, c.Want)
+		}
+	}
+}
+
+// checkConformance evaluates every case in domain and reports each one that
+// does not produce the expected value
+func checkConformance(t *testing.T, cases []conformanceCase, domain calculator.Domain) {
+	t.Helper()
+
+	for _, c := range cases {
+		prog, err := Compile(c.Expr)
+		if err != nil {
+			t.Errorf("%s: %v (%s)", c.Expr, err, c.Rule)
+			continue
+		}
+
+		result, err := prog.EvalIn(context.Background(), domain, nil)
+		if err != nil {
+			t.Errorf("%s: %v (%s)", c.Expr, err, c.Rule)
+			continue
+		}
+		got := result.Float64()
+
+		if math.Abs(got-c.Want) > 1e-12*math.Max(1, math.Abs(c.Want)) {
+			t.Errorf("%s = %v, want %v (%s)", c.Expr, got, c.Want, c.Rule)
+		}
+	}
+}
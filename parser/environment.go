@@ -0,0 +1,312 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// Environment holds everything an expression is evaluated against:
+// variables, user-defined functions, the last result (ans), the number
+// domain, the angle mode, the policy for NaN and infinities and resource
+// limits. Functions not defined in the environment are looked
+// up in its shared Library. An Environment is safe for concurrent use; give
+// each session its own environment over a common library.
+type Environment struct {
+	mu        sync.RWMutex
+	library   *Library
+	variables map[string]calculator.Value
+	ans       calculator.Value
+	functions map[string]*UserFunction
+	domain    calculator.Domain
+	angleMode string // "deg" or "rad"
+	special   string // "strict" or "ieee"
+	limits    Limits
+
+	warnAmbiguous bool
+}
+
+// NewEnvironment creates an empty environment. lib may be nil if no
+// functions are shared.
+func NewEnvironment(lib *Library) *Environment {
+	return &Environment{
+		library:   lib,
+		variables: make(map[string]calculator.Value),
+		ans:       calculator.Float(0),
+		functions: make(map[string]*UserFunction),
+		domain:    calculator.FloatDomain{},
+		angleMode: "rad",
+		special:   "strict",
+		limits:    DefaultLimits,
+	}
+}
+
+// Library returns the shared function library, which may be nil
+func (env *Environment) Library() *Library {
+	return env.library
+}
+
+func (env *Environment) SetAngleMode(mode string) {
+	if mode != "deg" && mode != "rad" {
+		return
+	}
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.angleMode = mode
+}
+
+func (env *Environment) AngleMode() string {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	return env.angleMode
+}
+
+// SetSpecialValues chooses what happens when a result is NaN or infinite:
+// "strict" reports an error naming the operation, and "ieee" lets the value
+// propagate as IEEE-754 defines, so that 1/0 is inf and sqrt(-1) is nan.
+// "ieee" only applies in domains that implement calculator.SpecialValues;
+// the others are always strict.
+func (env *Environment) SetSpecialValues(policy string) {
+	if policy != "strict" && policy != "ieee" {
+		return
+	}
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.special = policy
+}
+
+// SpecialValues returns the policy for NaN and infinite results
+func (env *Environment) SpecialValues() string {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	return env.special
+}
+
+// SetDomain selects the number system used for arithmetic, e.g.
+// calculator.FloatDomain{} or calculator.NewBigDomain(50)
+func (env *Environment) SetDomain(domain calculator.Domain) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.domain = domain
+}
+
+// Domain returns the number system used for arithmetic
+func (env *Environment) Domain() calculator.Domain {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	return env.domain
+}
+
+// SetLimits replaces the resource limits applied to each evaluation
+func (env *Environment) SetLimits(limits Limits) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.limits = limits
+}
+
+// Limits returns the resource limits applied to each evaluation
+func (env *Environment) Limits() Limits {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	return env.limits
+}
+
+// SetWarnAmbiguous enables warnings for input such as 1/2x, where implicit
+// multiplication follows a division
+func (env *Environment) SetWarnAmbiguous(enabled bool) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.warnAmbiguous = enabled
+}
+
+// SetAns sets the value of the ans constant
+func (env *Environment) SetAns(value calculator.Value) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.ans = value
+}
+
+// Ans returns the result of the last successful evaluation
+func (env *Environment) Ans() calculator.Value {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	return env.ans
+}
+
+func (env *Environment) SetVariable(name string, value float64) error {
+	return env.SetValue(name, calculator.Float(value))
+}
+
+func (env *Environment) GetVariable(name string) (float64, bool) {
+	val, exists := env.GetValue(name)
+	if !exists {
+		return 0, false
+	}
+	return val.Float64(), true
+}
+
+// SetValue stores a variable without converting it to float64
+func (env *Environment) SetValue(name string, value calculator.Value) error {
+	if !calculator.IsValidVariableName(name) {
+		return fmt.Errorf("invalid variable name: %s", name)
+	}
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.variables[name] = value
+	return nil
+}
+
+// GetValue returns a variable as it was stored
+func (env *Environment) GetValue(name string) (calculator.Value, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	val, exists := env.variables[name]
+	return val, exists
+}
+
+// DeleteVariable removes a variable, reporting whether it existed
+func (env *Environment) DeleteVariable(name string) bool {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	_, ok := env.variables[name]
+	delete(env.variables, name)
+	return ok
+}
+
+func (env *Environment) ClearVariables() {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.variables = make(map[string]calculator.Value)
+}
+
+// Define parses a definition of the form "name(params) = body" and stores it
+// in the environment
+func (env *Environment) Define(definition string) error {
+	name, params, body, err := ParseDefinition(definition)
+	if err != nil {
+		return err
+	}
+	return env.DefineFunction(name, params, body)
+}
+
+// DefineFunction compiles body and stores it as a callable function. It
+// shadows any library function with the same name.
+func (env *Environment) DefineFunction(name string, params []string, body string) error {
+	fn, err := newUserFunction(name, params, body)
+	if err != nil {
+		return err
+	}
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.functions[name] = fn
+	return nil
+}
+
+// GetFunction returns the function with the given name, looking in the
+// environment before the library
+func (env *Environment) GetFunction(name string) (*UserFunction, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	return env.lookupFunction(name)
+}
+
+// lookupFunction is GetFunction for callers already holding env.mu
+func (env *Environment) lookupFunction(name string) (*UserFunction, bool) {
+	if fn, ok := env.functions[name]; ok {
+		return fn, true
+	}
+	if env.library != nil {
+		return env.library.GetFunction(name)
+	}
+	return nil, false
+}
+
+// Functions returns the functions visible in the environment, including
+// library functions it does not shadow, sorted by name
+func (env *Environment) Functions() []*UserFunction {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	visible := make(map[string]*UserFunction, len(env.functions))
+	if env.library != nil {
+		for _, fn := range env.library.Functions() {
+			visible[fn.Name] = fn
+		}
+	}
+	for name, fn := range env.functions {
+		visible[name] = fn
+	}
+	return sortedFunctions(visible)
+}
+
+// DeleteFunction removes a function defined in the environment, reporting
+// whether it existed. Library functions are not affected.
+func (env *Environment) DeleteFunction(name string) bool {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	_, ok := env.functions[name]
+	delete(env.functions, name)
+	return ok
+}
+
+// ClearFunctions removes the functions defined in the environment
+func (env *Environment) ClearFunctions() {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.functions = make(map[string]*UserFunction)
+}
+
+// Evaluate compiles and evaluates expr, stopping with the context's error if
+// ctx is cancelled. On success the result becomes the new value of ans.
+func (env *Environment) Evaluate(ctx context.Context, expr string) (calculator.Value, []Warning, error) {
+	limits := env.Limits()
+
+	prog, err := CompileWithLimits(expr, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, warnAmbiguous, err := env.eval(ctx, prog, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env.SetAns(result)
+
+	if !warnAmbiguous {
+		return result, nil, nil
+	}
+	return result, prog.Warnings(), nil
+}
+
+// eval runs prog against a copy of the environment taken under the read
+// lock. Variables and functions cannot change part way through an
+// evaluation, and a long evaluation does not hold up Set, Define or Restore.
+func (env *Environment) eval(ctx context.Context, prog *Program, limits Limits) (calculator.Value, bool, error) {
+	env.mu.RLock()
+	// Only domains with NaN and infinities can follow the IEEE policy
+	_, special := env.domain.(calculator.SpecialValues)
+	snapshot := &Environment{
+		library:   env.library,
+		functions: maps.Clone(env.functions),
+	}
+	ev := &evaluator{
+		ctx:       ctx,
+		limits:    limits,
+		ops:       new(int),
+		domain:    env.domain,
+		angleMode: env.angleMode,
+		ieee:      env.special == "ieee" && special,
+		variables: maps.Clone(env.variables),
+		ans:       env.ans,
+		functions: snapshot.lookupFunction,
+	}
+	warnAmbiguous := env.warnAmbiguous
+	env.mu.RUnlock()
+
+	result, err := ev.run(prog.tree)
+	return result, warnAmbiguous, err
+}
@@ -0,0 +1,193 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// TestEnvironmentsShareLibrary runs many sessions at once, each with its own
+// environment over one library, as a server would. Run it with -race.
+func TestEnvironmentsShareLibrary(t *testing.T) {
+	lib := NewLibrary()
+	if err := lib.Define("sq(x) = x * x"); err != nil {
+		t.Fatal(err)
+	}
+
+	const sessions = 32
+	const rounds = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, sessions)
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- runSession(lib, i, rounds)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// runSession defines, evaluates, saves and restores in a fresh environment,
+// while also changing the shared library
+func runSession(lib *Library, i, rounds int) error {
+	ctx := context.Background()
+	env := NewEnvironment(lib)
+	if i%2 == 1 {
+		env.SetDomain(calculator.NewBigDomain(30))
+	}
+
+	for r := 0; r < rounds; r++ {
+		// Every session replaces the same library function with an
+		// equivalent definition, so results do not depend on the order
+		if err := lib.Define("cube(x) = x * sq(x)"); err != nil {
+			return err
+		}
+		if err := env.Define(fmt.Sprintf("own(x) = x + %d", i)); err != nil {
+			return err
+		}
+		if err := env.SetVariable("n", float64(r)); err != nil {
+			return err
+		}
+
+		got, _, err := env.Evaluate(ctx, "own(cube(n)) + ans - ans")
+		if err != nil {
+			return fmt.Errorf("session %d: %v", i, err)
+		}
+		if want := float64(r*r*r + i); got.Float64() != want {
+			return fmt.Errorf("session %d: own(cube(%d)) = %v, want %v", i, r, got, want)
+		}
+
+		restored := NewEnvironment(lib)
+		if err := restored.Restore(env.State()); err != nil {
+			return fmt.Errorf("session %d: restore: %v", i, err)
+		}
+		got, _, err = restored.Evaluate(ctx, "own(n)")
+		if err != nil {
+			return fmt.Errorf("session %d: after restore: %v", i, err)
+		}
+		if want := float64(r + i); got.Float64() != want {
+			return fmt.Errorf("session %d: restored own(%d) = %v, want %v", i, r, got, want)
+		}
+	}
+	return nil
+}
+
+// TestStateKeepsExactValues saves rational and big-mode variables to JSON,
+// as save does, and checks that they come back with every digit
+func TestStateKeepsExactValues(t *testing.T) {
+	ctx := context.Background()
+	env := NewEnvironment(NewLibrary())
+	values := map[string]struct {
+		domain calculator.Domain
+		expr   string
+	}{
+		"third": {calculator.RatDomain{}, "1/3"},
+		"big":   {calculator.NewBigDomain(100), "sqrt(2)"},
+		"tiny":  {calculator.NewBigDomain(30), "1/3 * 10^-40"},
+	}
+	for name, v := range values {
+		env.SetDomain(v.domain)
+		value, _, err := env.Evaluate(ctx, v.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", v.expr, err)
+		}
+		if err := env.SetValue(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := json.Marshal(env.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewEnvironment(NewLibrary())
+	if err := restored.Restore(state); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range values {
+		want, _ := env.GetValue(name)
+		got, ok := restored.GetValue(name)
+		if !ok {
+			t.Errorf("%s was not restored", name)
+			continue
+		}
+		if fmt.Sprintf("%T", got) != fmt.Sprintf("%T", want) || got.String() != want.String() {
+			t.Errorf("%s restored as %T %s, want %T %s", name, got, got, want, want)
+			continue
+		}
+		if x, ok := want.(calculator.BigFloat); ok && got.(calculator.BigFloat).Big().Cmp(x.Big()) != 0 {
+			t.Errorf("%s restored as %s, want every bit of %s", name, got.(calculator.BigFloat).Big().Text('g', -1), x.Big().Text('g', -1))
+		}
+	}
+}
+
+// pausingDomain runs hook before its first binary operation
+type pausingDomain struct {
+	calculator.FloatDomain
+	hook *func()
+}
+
+func (d pausingDomain) Binary(ctx context.Context, op string, x, y calculator.Value) (calculator.Value, error) {
+	if *d.hook != nil {
+		hook := *d.hook
+		*d.hook = nil
+		hook()
+	}
+	return d.FloatDomain.Binary(ctx, op, x, y)
+}
+
+// TestEvaluateDoesNotHoldLock changes the environment from inside an
+// evaluation. The evaluation must neither block those changes nor see them.
+func TestEvaluateDoesNotHoldLock(t *testing.T) {
+	env := NewEnvironment(nil)
+	if err := env.SetValue("x", calculator.Float(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Define("f(y) = y + 10"); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := func() {
+		if !env.mu.TryLock() {
+			t.Error("environment is locked during evaluation")
+			return
+		}
+		env.mu.Unlock()
+		if err := env.SetValue("x", calculator.Float(2)); err != nil {
+			t.Error(err)
+		}
+		if err := env.Define("f(y) = y + 20"); err != nil {
+			t.Error(err)
+		}
+	}
+	env.SetDomain(pausingDomain{hook: &hook})
+
+	got, _, err := env.Evaluate(context.Background(), "x + 0 + f(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Float64() != 11 {
+		t.Errorf("got %v, want 11 from the values at the start", got)
+	}
+	if got, _ := env.GetValue("x"); got.Float64() != 2 {
+		t.Errorf("x = %v after evaluation, want 2", got)
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is a parse or evaluation error tied to the span [Pos, End) of the
+// original input, measured in bytes. Errors raised inside a user-defined
+// function point at the call site and name the function in Func. Err holds
+// the underlying cause, such as a *DepthLimitError or context.Canceled.
+type Error struct {
+	Pos  int
+	End  int
+	Msg  string
+	Func string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos+1, e.Message())
+}
+
+// Message returns the error without its position, naming the function it
+// was raised in unless the message already does, as a *RecursionLimitError
+// does
+func (e *Error) Message() string {
+	var rerr *RecursionLimitError
+	if e.Func == "" || (errors.As(e.Err, &rerr) && rerr.Function == e.Func) {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s (in function %s)", e.Msg, e.Func)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errorf creates an Error covering [pos, end)
+func errorf(pos, end int, format string, args ...interface{}) *Error {
+	if end < pos {
+		end = pos
+	}
+	return &Error{Pos: pos, End: end, Msg: fmt.Sprintf(format, args...)}
+}
+
+// tokenError creates an Error covering a single token
+func tokenError(tok Token, format string, args ...interface{}) *Error {
+	return errorf(tok.Pos, tok.Pos+len(tok.Text), format, args...)
+}
+
+// errorAt attaches the span of node to err unless it already has a position
+func errorAt(node Node, err error) error {
+	var perr *Error
+	if errors.As(err, &perr) {
+		return err
+	}
+	return spanError(node.Pos(), node.End(), err)
+}
+
+// spanError wraps err in an Error covering [pos, end)
+func spanError(pos, end int, err error) *Error {
+	perr := errorf(pos, end, "%s", err.Error())
+	perr.Err = err
+	return perr
+}
@@ -0,0 +1,543 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+	"github.com/Oluwaseyi89/calculator-built-with-go/utils"
+)
+
+// builtinConstants are the names of the constants the domains provide, all
+// but the integer modes, where pi and e are not integers
+var builtinConstants = map[string]bool{
+	"pi": true,
+	"π":  true,
+	"e":  true,
+}
+
+// evaluator holds the state needed to walk one syntax tree. It only reads
+// from its maps, so evaluators built over shared maps may run concurrently.
+type evaluator struct {
+	ctx    context.Context
+	limits Limits
+	ops    *int // shared with the evaluators of called functions
+
+	domain    calculator.Domain
+	angleMode string
+	ieee      bool // let NaN and infinities through instead of failing
+	variables map[string]calculator.Value
+	ans       calculator.Value                        // nil outside an Environment
+	functions func(name string) (*UserFunction, bool) // may be nil
+
+	locals map[string]calculator.Value // parameters of the function being called
+	depth  int
+}
+
+// run evaluates a whole syntax tree, failing fast if the context is done
+func (ev *evaluator) run(tree Node) (calculator.Value, error) {
+	if err := ev.ctx.Err(); err != nil {
+		return nil, spanError(tree.Pos(), tree.End(), err)
+	}
+	return ev.eval(tree)
+}
+
+// eval walks the syntax tree and computes its value. Errors are tagged
+// with the span of the innermost node that failed.
+func (ev *evaluator) eval(node Node) (calculator.Value, error) {
+	if err := ev.step(); err != nil {
+		return nil, errorAt(node, err)
+	}
+
+	val, err := ev.evalNode(node)
+	if err != nil {
+		return nil, errorAt(node, err)
+	}
+	return val, nil
+}
+
+// step counts one operation against the limits and periodically checks
+// whether the context has been cancelled
+func (ev *evaluator) step() error {
+	*ev.ops++
+	if ev.limits.MaxOperations > 0 && *ev.ops > ev.limits.MaxOperations {
+		return &OperationLimitError{Limit: ev.limits.MaxOperations}
+	}
+	if *ev.ops%256 == 0 {
+		return ev.ctx.Err()
+	}
+	return nil
+}
+
+func (ev *evaluator) evalNode(node Node) (calculator.Value, error) {
+	switch n := node.(type) {
+	case *NumberLit:
+		if calculator.RadixPrefix(n.Text) != 0 {
+			if _, ok := ev.domain.(calculator.ProgrammerDomain); !ok {
+				return nil, fmt.Errorf("hex, octal and binary literals need programmer mode")
+			}
+		}
+		return ev.domain.Literal(n.Text)
+
+	case *StringLit:
+		return nil, fmt.Errorf("a string can only be passed to a function such as frombase")
+
+	case *Ident:
+		return ev.lookup(n.Name)
+
+	case *ParenExpr:
+		return ev.eval(n.X)
+
+	case *UnaryExpr:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "-":
+			return ev.domain.Negate(x)
+		case notKeyword:
+			return ev.domain.Bool(!ev.domain.Truthy(x)), nil
+		case "~":
+			bw, ok := ev.domain.(calculator.Bitwise)
+			if !ok {
+				return nil, fmt.Errorf("bitwise operators need programmer mode")
+			}
+			return bw.Complement(x)
+		default:
+			return nil, fmt.Errorf("unknown operator: %s", n.Op)
+		}
+
+	case *BinaryExpr:
+		if n.Implicit {
+			if err := ev.checkRadixPrefix(n); err != nil {
+				return nil, err
+			}
+		}
+		a, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+
+		// && and || only evaluate their right operand when needed
+		switch {
+		case n.Op == "&&" && !ev.domain.Truthy(a):
+			return ev.domain.Bool(false), nil
+		case n.Op == "||" && ev.domain.Truthy(a):
+			return ev.domain.Bool(true), nil
+		}
+
+		b, err := ev.eval(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "&&" || n.Op == "||" {
+			// Reached only when the left operand did not decide the result
+			return ev.domain.Bool(ev.domain.Truthy(b)), nil
+		}
+		if n.Op == "±" {
+			maker, ok := ev.domain.(calculator.UncertainMaker)
+			if !ok {
+				return nil, fmt.Errorf("values with ± need uncertainty or interval mode")
+			}
+			return maker.Uncertain(a, b)
+		}
+		if binaryOps[n.Op].bitwise {
+			bw, ok := ev.domain.(calculator.Bitwise)
+			if !ok {
+				return nil, fmt.Errorf("bitwise operators need programmer mode")
+			}
+			return bw.Bitwise(n.Op, a, b)
+		}
+		result, err := ev.special(ev.domain.Binary(ev.ctx, n.Op, a, b))
+		if err != nil {
+			return nil, err
+		}
+		return ev.checkBinary(n.Op, a, b, result)
+
+	case *PostfixExpr:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		if f := x.Float64(); ev.limits.MaxFactorial > 0 && f > ev.limits.MaxFactorial {
+			return nil, &FactorialLimitError{Arg: f, Limit: ev.limits.MaxFactorial}
+		}
+
+		// float64 operands overflow long before the limit, which is only
+		// an error under the strict policy
+		f, isFloat := x.(calculator.Float)
+		check := isFloat && !ev.ieee
+		var result calculator.Value
+		switch n.Op {
+		case "!":
+			if check {
+				if err := utils.ValidateFactorialArgument(float64(f)); err != nil {
+					return nil, err
+				}
+			}
+			result, err = ev.special(ev.domain.Factorial(ev.ctx, x))
+		case "!!":
+			if check {
+				if err := utils.ValidateDoubleFactorialArgument(float64(f)); err != nil {
+					return nil, err
+				}
+			}
+			result, err = ev.special(ev.domain.DoubleFactorial(ev.ctx, x))
+		default:
+			return nil, fmt.Errorf("unknown operator: %s", n.Op)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ev.checkResult("factorial", result, x)
+
+	case *IntervalExpr:
+		maker, ok := ev.domain.(calculator.IntervalMaker)
+		if !ok {
+			return nil, fmt.Errorf("interval literals need interval mode")
+		}
+		lo, err := ev.eval(n.Lo)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := ev.eval(n.Hi)
+		if err != nil {
+			return nil, err
+		}
+		return maker.Interval(lo, hi)
+
+	case *CallExpr:
+		if strings.ToLower(n.Name) == "if" {
+			return ev.evaluateIf(n)
+		}
+
+		args := make([]calculator.Value, len(n.Args))
+		text := false
+		for i, arg := range n.Args {
+			// Strings are only evaluated as arguments
+			if s, ok := arg.(*StringLit); ok {
+				args[i] = calculator.Text(s.Value)
+				text = true
+				continue
+			}
+			val, err := ev.eval(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = val
+		}
+		if ev.functions != nil {
+			if fn, ok := ev.functions(n.Name); ok {
+				if text {
+					return nil, fmt.Errorf("%s does not take strings", fn.Name)
+				}
+				return ev.callUserFunction(n, fn, args)
+			}
+		}
+		if !calculator.IsFunction(n.Name) && len(args) == 1 && !text {
+			// x(y) with a variable x is an implicit multiplication
+			if val, err := ev.lookup(n.Name); err == nil {
+				result, err := ev.domain.Binary(ev.ctx, "*", val, args[0])
+				if err != nil {
+					return nil, err
+				}
+				return ev.checkBinary("*", val, args[0], result)
+			}
+		}
+		return ev.evaluateFunction(strings.ToLower(n.Name), args)
+
+	default:
+		return nil, fmt.Errorf("unsupported expression node %T", node)
+	}
+}
+
+// lookup resolves an identifier to a variable or constant value
+func (ev *evaluator) lookup(name string) (calculator.Value, error) {
+	if val, ok := ev.locals[name]; ok {
+		return val, nil
+	}
+	if val, ok := ev.variables[name]; ok {
+		return ev.domain.Convert(val)
+	}
+
+	lower := strings.ToLower(name)
+	if lower == "ans" && ev.ans != nil {
+		return ev.domain.Convert(ev.ans)
+	}
+	if val, ok := ev.domain.Constant(lower); ok {
+		return val, nil
+	}
+	if builtinConstants[lower] {
+		return nil, fmt.Errorf("%s is not available in %s mode, which only has integers", name, ev.domain.Name())
+	}
+	return nil, fmt.Errorf("unknown variable: %s", name)
+}
+
+// evaluateIf evaluates the condition and then only the selected branch
+func (ev *evaluator) evaluateIf(call *CallExpr) (calculator.Value, error) {
+	fn, _ := calculator.LookupFunction("if")
+	if err := fn.CheckArgs(len(call.Args)); err != nil {
+		return nil, err
+	}
+
+	cond, err := ev.eval(call.Args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if ev.domain.Truthy(cond) {
+		return ev.eval(call.Args[1])
+	}
+	return ev.eval(call.Args[2])
+}
+
+// callUserFunction evaluates a user-defined function body with its
+// parameters bound to args
+func (ev *evaluator) callUserFunction(call *CallExpr, fn *UserFunction, args []calculator.Value) (calculator.Value, error) {
+	if len(args) != len(fn.Params) {
+		return nil, fmt.Errorf("%s expects %d argument(s)", fn.Name, len(fn.Params))
+	}
+	if ev.limits.MaxRecursion > 0 && ev.depth >= ev.limits.MaxRecursion {
+		return nil, &RecursionLimitError{Limit: ev.limits.MaxRecursion, Function: fn.Name}
+	}
+
+	locals := make(map[string]calculator.Value, len(args))
+	for i, param := range fn.Params {
+		locals[param] = args[i]
+	}
+
+	child := *ev
+	child.locals = locals
+	child.depth++
+
+	result, err := child.eval(fn.prog.tree)
+	if err != nil {
+		// Positions inside the body refer to the definition, not the input,
+		// so point at the call and remember where the error came from
+		var perr *Error
+		if !errors.As(err, &perr) {
+			return nil, err
+		}
+		inner := perr.Func
+		if inner == "" {
+			inner = fn.Name
+		}
+		return nil, &Error{Pos: call.Pos(), End: call.End(), Msg: perr.Msg, Func: inner, Err: perr.Err}
+	}
+	return result, nil
+}
+
+// evaluateFunction dispatches a call through the function registry,
+// converting angles according to the current angle mode
+func (ev *evaluator) evaluateFunction(name string, args []calculator.Value) (calculator.Value, error) {
+	fn, ok := calculator.LookupFunction(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown function: %s", name)
+	}
+	if err := fn.CheckArgs(len(args)); err != nil {
+		return nil, err
+	}
+	if impl, ok := calculator.ImplementationOf[calculator.ValueFunc](fn); ok {
+		return impl(ev.domain, args)
+	}
+	for _, arg := range args {
+		if _, ok := arg.(calculator.Text); ok {
+			return nil, fmt.Errorf("%s does not take strings", fn.Name)
+		}
+	}
+
+	deg := ev.angleMode == "deg"
+	if fn.AngleIn && deg {
+		for i := range args {
+			rad, err := ev.convertAngle(args[i], "180", "pi")
+			if err != nil {
+				return nil, err
+			}
+			args[i] = rad
+		}
+	}
+	if fn.AngleIn {
+		for _, arg := range args {
+			if ev.rejects(arg) {
+				return nil, utils.ValidateAngle(arg.Float64(), ev.angleMode)
+			}
+		}
+	}
+
+	result, err := ev.special(ev.domain.Call(ev.ctx, fn, args))
+	if err != nil {
+		return nil, err
+	}
+
+	if fn.AngleOut && deg {
+		if result, err = ev.convertAngle(result, "pi", "180"); err != nil {
+			return nil, err
+		}
+	}
+	return ev.checkResult(fn.Name, result, args...)
+}
+
+// checkRadixPrefix rejects a malformed literal such as 0xG or 0b2 in
+// programmer mode. The lexer only reads a prefix followed by a digit of its
+// base, so these arrive as 0 times a name starting with x, o or b.
+func (ev *evaluator) checkRadixPrefix(n *BinaryExpr) error {
+	if _, ok := ev.domain.(calculator.ProgrammerDomain); !ok {
+		return nil
+	}
+
+	zero := n.X
+	for {
+		switch x := zero.(type) {
+		case *BinaryExpr:
+			zero = x.Y
+			continue
+		case *UnaryExpr:
+			zero = x.X
+			continue
+		}
+		break
+	}
+	if lit, ok := zero.(*NumberLit); !ok || lit.Text != "0" || lit.End() != n.OpPos {
+		return nil
+	}
+
+	name := ""
+	for y := n.Y; name == ""; {
+		switch x := y.(type) {
+		case *BinaryExpr:
+			y = x.X
+		case *PostfixExpr:
+			y = x.X
+		case *Ident:
+			name = x.Name
+		case *CallExpr:
+			name = x.Name
+		default:
+			return nil
+		}
+	}
+	if len(name) < 2 {
+		return nil
+	}
+	base := calculator.RadixPrefix("0" + name[:1] + "0")
+	if base == 0 {
+		return nil
+	}
+	return errorf(zero.Pos(), n.OpPos+len(name), "invalid %s literal: 0%s", radixNames[base], name)
+}
+
+// checkBinary enforces the strict policy on the result of a binary
+// operator, explaining the usual causes of NaN and infinities
+func (ev *evaluator) checkBinary(op string, a, b, result calculator.Value) (calculator.Value, error) {
+	if !ev.rejects(result) {
+		return result, nil
+	}
+
+	x, y := a.Float64(), b.Float64()
+	switch op {
+	case "/":
+		if err := utils.ValidateDivision(x, y); err != nil {
+			return nil, err
+		}
+	case "%":
+		if y == 0 {
+			return nil, fmt.Errorf("modulus by zero")
+		}
+	case "^":
+		if err := utils.ValidatePower(x, y); err != nil {
+			return nil, err
+		}
+	}
+	return ev.checkResult("operator "+op, result, a, b)
+}
+
+// checkResult enforces the strict policy: a NaN or infinite result of the
+// named operation is an error. Under the IEEE policy it is returned as is.
+func (ev *evaluator) checkResult(name string, result calculator.Value, args ...calculator.Value) (calculator.Value, error) {
+	if !ev.rejects(result) {
+		return result, nil
+	}
+	x := result.Float64()
+	if math.IsNaN(x) {
+		return nil, fmt.Errorf("%s gives NaN", name)
+	}
+
+	infinity := "infinity"
+	if x < 0 {
+		infinity = "-infinity"
+	}
+	for _, arg := range args {
+		if ev.rejects(arg) {
+			return nil, fmt.Errorf("%s gives %s", name, infinity)
+		}
+	}
+	return nil, fmt.Errorf("%s overflows to %s", name, infinity)
+}
+
+// special applies the IEEE policy to an error from the domain: an argument
+// outside an operation's domain gives NaN or an infinity instead, as
+// IEEE-754 defines, e.g. sqrt(-1) = nan and log(0) = -inf
+func (ev *evaluator) special(result calculator.Value, err error) (calculator.Value, error) {
+	var domainErr *calculator.DomainError
+	if err == nil || !ev.ieee || !errors.As(err, &domainErr) {
+		return result, err
+	}
+	return ev.domain.(calculator.SpecialValues).Special(domainErr.Result), nil
+}
+
+// rejects reports whether the strict policy forbids x: a NaN or infinite
+// value, or an interval with such a bound. Exact integers and fractions,
+// factorisations among them, cannot be either, even when too large for a
+// float64.
+func (ev *evaluator) rejects(x calculator.Value) bool {
+	if ev.ieee {
+		return false
+	}
+	special := func(f float64) bool {
+		return math.IsNaN(f) || math.IsInf(f, 0)
+	}
+	switch v := x.(type) {
+	case interface{ Int() *big.Int }, interface{ Rat() *big.Rat }:
+		return false
+	case calculator.Complex:
+		return special(real(v)) || special(imag(v))
+	case calculator.Interval:
+		return special(v.Lo) || special(v.Hi)
+	case calculator.BigFloat:
+		return v.Big().IsInf()
+	}
+	return special(x.Float64())
+}
+
+// convertAngle scales x by to/from, where each is "pi" or "180", so that
+// degrees are converted at the domain's precision
+func (ev *evaluator) convertAngle(x calculator.Value, from, to string) (calculator.Value, error) {
+	factor := func(name string) (calculator.Value, error) {
+		if val, ok := ev.domain.Constant(name); ok {
+			return val, nil
+		}
+		// Converted rather than read as a literal, which sig-fig mode
+		// would count as a measurement with two significant figures
+		return ev.domain.Convert(calculator.Float(180))
+	}
+
+	f, err := factor(from)
+	if err != nil {
+		return nil, err
+	}
+	t, err := factor(to)
+	if err != nil {
+		return nil, err
+	}
+
+	x, err = ev.domain.Binary(ev.ctx, "*", x, t)
+	if err != nil {
+		return nil, err
+	}
+	return ev.domain.Binary(ev.ctx, "/", x, f)
+}
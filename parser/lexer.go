@@ -0,0 +1,280 @@
+package parser
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// TokenKind identifies the lexical class of a token
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenNumber
+	TokenIdent
+	TokenOperator
+	TokenLParen
+	TokenRParen
+	TokenLBracket
+	TokenRBracket
+	TokenComma
+	TokenString
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenEOF:
+		return "end of input"
+	case TokenNumber:
+		return "number"
+	case TokenIdent:
+		return "identifier"
+	case TokenOperator:
+		return "operator"
+	case TokenLParen:
+		return "'('"
+	case TokenRParen:
+		return "')'"
+	case TokenLBracket:
+		return "'['"
+	case TokenRBracket:
+		return "']'"
+	case TokenComma:
+		return "','"
+	case TokenString:
+		return "string"
+	default:
+		return "unknown token"
+	}
+}
+
+// Token is a single lexical element of an expression. Pos is the byte
+// offset of the token in the original input.
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Pos   int
+	Value float64 // parsed value for TokenNumber
+}
+
+// operatorChars lists the single-character operators understood by the lexer
+const operatorChars = "+-*/^%!<>±&|~"
+
+// longOperators are matched before single-character operators, longest
+// first, so that >>> is not read as >> >
+var longOperators = []string{">>>", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||"}
+
+// plusMinusASCII is read as ± for keyboards without it
+const plusMinusASCII = "+/-"
+
+// Tokenize splits an expression into typed tokens. The returned slice always
+// ends with a TokenEOF token.
+func Tokenize(input string) ([]Token, error) {
+	lx := &lexer{input: input}
+	var tokens []Token
+
+	for {
+		tok, err := lx.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Kind == TokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (lx *lexer) next() (Token, error) {
+	lx.skipSpace()
+
+	if lx.pos >= len(lx.input) {
+		return Token{Kind: TokenEOF, Pos: lx.pos}, nil
+	}
+
+	start := lx.pos
+	ch, size := utf8.DecodeRuneInString(lx.input[lx.pos:])
+
+	switch {
+	case isDigit(ch) || (ch == '.' && isDigit(lx.peekAt(lx.pos+1))):
+		return lx.number()
+	case isIdentStart(ch):
+		return lx.ident(), nil
+	case ch == '(':
+		lx.pos += size
+		return Token{Kind: TokenLParen, Text: "(", Pos: start}, nil
+	case ch == ')':
+		lx.pos += size
+		return Token{Kind: TokenRParen, Text: ")", Pos: start}, nil
+	case ch == '[':
+		lx.pos += size
+		return Token{Kind: TokenLBracket, Text: "[", Pos: start}, nil
+	case ch == ']':
+		lx.pos += size
+		return Token{Kind: TokenRBracket, Text: "]", Pos: start}, nil
+	case ch == ',':
+		lx.pos += size
+		return Token{Kind: TokenComma, Text: ",", Pos: start}, nil
+	case ch == '"':
+		return lx.str()
+	}
+
+	// +/- is the ASCII spelling of ±; it is not valid input otherwise
+	if strings.HasPrefix(lx.input[lx.pos:], plusMinusASCII) {
+		lx.pos += len(plusMinusASCII)
+		return Token{Kind: TokenOperator, Text: plusMinusASCII, Pos: start}, nil
+	}
+
+	for _, op := range longOperators {
+		if strings.HasPrefix(lx.input[lx.pos:], op) {
+			lx.pos += len(op)
+			return Token{Kind: TokenOperator, Text: op, Pos: start}, nil
+		}
+	}
+
+	if strings.ContainsRune(operatorChars, ch) {
+		lx.pos += size
+		return Token{Kind: TokenOperator, Text: string(ch), Pos: start}, nil
+	}
+
+	if ch == '=' {
+		return Token{}, errorf(start, start+size, "unexpected '='; use == for comparison")
+	}
+
+	return Token{}, errorf(start, start+size, "unexpected character %q", ch)
+}
+
+// str scans a string in double quotes, such as the digits passed to
+// frombase. There are no escapes, since digits never need them.
+func (lx *lexer) str() (Token, error) {
+	start := lx.pos
+	end := strings.IndexByte(lx.input[start+1:], '"')
+	if end < 0 {
+		return Token{}, errorf(start, len(lx.input), "missing closing quote")
+	}
+	lx.pos = start + end + 2
+	return Token{Kind: TokenString, Text: lx.input[start:lx.pos], Pos: start}, nil
+}
+
+func (lx *lexer) skipSpace() {
+	for lx.pos < len(lx.input) {
+		ch, size := utf8.DecodeRuneInString(lx.input[lx.pos:])
+		if !unicode.IsSpace(ch) {
+			return
+		}
+		lx.pos += size
+	}
+}
+
+// number scans a decimal literal with an optional fraction and exponent.
+// The exponent marker is only consumed when it is followed by digits, so
+// "2e" lexes as the number 2 followed by the identifier e.
+func (lx *lexer) number() (Token, error) {
+	start := lx.pos
+	if calculator.RadixPrefix(lx.input[lx.pos:]) != 0 {
+		return lx.radixNumber()
+	}
+
+	lx.digits()
+	if lx.peekAt(lx.pos) == '.' {
+		lx.pos++
+		lx.digits()
+	}
+
+	if c := lx.peekAt(lx.pos); c == 'e' || c == 'E' {
+		i := lx.pos + 1
+		if s := lx.peekAt(i); s == '+' || s == '-' {
+			i++
+		}
+		if isDigit(lx.peekAt(i)) {
+			lx.pos = i
+			lx.digits()
+		}
+	}
+
+	if lx.peekAt(lx.pos) == '.' {
+		for c := lx.peekAt(lx.pos); isDigit(c) || c == '.'; c = lx.peekAt(lx.pos) {
+			lx.pos++
+		}
+		return Token{}, errorf(start, lx.pos, "invalid number with multiple decimal points: %s", lx.input[start:lx.pos])
+	}
+
+	text := lx.input[start:lx.pos]
+
+	// Out of float64 range is left to the domain; big mode can represent it
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return Token{}, errorf(start, lx.pos, "invalid number: %s", text)
+	}
+
+	return Token{Kind: TokenNumber, Text: text, Pos: start, Value: value}, nil
+}
+
+// radixNumber scans a hex, octal or binary integer literal such as 0xFF,
+// 0o17 or 0b1010
+func (lx *lexer) radixNumber() (Token, error) {
+	start := lx.pos
+	base := calculator.RadixPrefix(lx.input[lx.pos:])
+
+	lx.pos += 2
+	for c := lx.peekAt(lx.pos); isDigit(c) || isIdentStart(c) || c == '.'; c = lx.peekAt(lx.pos) {
+		lx.pos++
+	}
+
+	text := lx.input[start:lx.pos]
+	n, ok := new(big.Int).SetString(text[2:], base)
+	if !ok {
+		return Token{}, errorf(start, lx.pos, "invalid %s literal: %s", radixNames[base], text)
+	}
+	value, _ := new(big.Float).SetInt(n).Float64()
+	return Token{Kind: TokenNumber, Text: text, Pos: start, Value: value}, nil
+}
+
+// radixNames names the bases that have a literal prefix
+var radixNames = map[int]string{2: "binary", 8: "octal", 16: "hex"}
+
+func (lx *lexer) digits() {
+	for isDigit(lx.peekAt(lx.pos)) {
+		lx.pos++
+	}
+}
+
+func (lx *lexer) ident() Token {
+	start := lx.pos
+	for lx.pos < len(lx.input) {
+		ch, size := utf8.DecodeRuneInString(lx.input[lx.pos:])
+		if !isIdentStart(ch) && !isDigit(ch) {
+			break
+		}
+		lx.pos += size
+	}
+	return Token{Kind: TokenIdent, Text: lx.input[start:lx.pos], Pos: start}
+}
+
+// peekAt returns the ASCII byte at offset i, or 0 past the end of input
+func (lx *lexer) peekAt(i int) rune {
+	if i >= len(lx.input) {
+		return 0
+	}
+	return rune(lx.input[i])
+}
+
+// Helper functions
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isIdentStart(ch rune) bool {
+	return ch == '_' || ch == 'π' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
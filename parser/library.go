@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"sort"
+	"sync"
+)
+
+// Library is a set of user-defined functions shared by many environments.
+// It is safe for concurrent use.
+type Library struct {
+	mu        sync.RWMutex
+	functions map[string]*UserFunction
+}
+
+func NewLibrary() *Library {
+	return &Library{functions: make(map[string]*UserFunction)}
+}
+
+// Define parses a definition of the form "name(params) = body" and stores it
+func (lib *Library) Define(definition string) error {
+	name, params, body, err := ParseDefinition(definition)
+	if err != nil {
+		return err
+	}
+	return lib.DefineFunction(name, params, body)
+}
+
+// DefineFunction compiles body and stores it as a callable function
+func (lib *Library) DefineFunction(name string, params []string, body string) error {
+	fn, err := newUserFunction(name, params, body)
+	if err != nil {
+		return err
+	}
+
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	lib.functions[name] = fn
+	return nil
+}
+
+// GetFunction returns the function with the given name
+func (lib *Library) GetFunction(name string) (*UserFunction, bool) {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	fn, ok := lib.functions[name]
+	return fn, ok
+}
+
+// Functions returns the library's functions sorted by name
+func (lib *Library) Functions() []*UserFunction {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	return sortedFunctions(lib.functions)
+}
+
+// DeleteFunction removes a function, reporting whether it existed
+func (lib *Library) DeleteFunction(name string) bool {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	_, ok := lib.functions[name]
+	delete(lib.functions, name)
+	return ok
+}
+
+// Clear removes all functions from the library
+func (lib *Library) Clear() {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	lib.functions = make(map[string]*UserFunction)
+}
+
+// sortedFunctions returns the values of funcs ordered by name
+func sortedFunctions(funcs map[string]*UserFunction) []*UserFunction {
+	list := make([]*UserFunction, 0, len(funcs))
+	for _, fn := range funcs {
+		list = append(list, fn)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// Limits bounds the resources one evaluation may use. A zero field means
+// that resource is not limited.
+type Limits struct {
+	MaxInputLength int     // bytes of expression text
+	MaxDepth       int     // nesting depth of the syntax tree
+	MaxOperations  int     // syntax tree nodes evaluated, including function bodies
+	MaxRecursion   int     // nested calls of user-defined functions
+	MaxFactorial   float64 // largest argument accepted by ! and !!
+}
+
+// DefaultLimits are used by Compile, NewParser and the REPL
+var DefaultLimits = Limits{
+	MaxInputLength: 10000,
+	MaxDepth:       200,
+	MaxOperations:  10000000,
+	MaxRecursion:   DefaultMaxRecursion,
+	MaxFactorial:   10000,
+}
+
+// InputLengthError reports an expression longer than Limits.MaxInputLength
+type InputLengthError struct {
+	Length int
+	Limit  int
+}
+
+func (e *InputLengthError) Error() string {
+	return fmt.Sprintf("expression is %d bytes long, limit is %d", e.Length, e.Limit)
+}
+
+// DepthLimitError reports nesting deeper than Limits.MaxDepth
+type DepthLimitError struct {
+	Limit int
+}
+
+func (e *DepthLimitError) Error() string {
+	return fmt.Sprintf("expression nested deeper than %d levels", e.Limit)
+}
+
+// OperationLimitError reports an evaluation that needed more than
+// Limits.MaxOperations steps
+type OperationLimitError struct {
+	Limit int
+}
+
+func (e *OperationLimitError) Error() string {
+	return fmt.Sprintf("evaluation exceeded %d operations", e.Limit)
+}
+
+// RecursionLimitError reports user-defined functions nested deeper than
+// Limits.MaxRecursion
+type RecursionLimitError struct {
+	Limit    int
+	Function string
+}
+
+func (e *RecursionLimitError) Error() string {
+	return fmt.Sprintf("maximum recursion depth (%d) exceeded in %s", e.Limit, e.Function)
+}
+
+// FactorialLimitError reports a factorial argument above Limits.MaxFactorial
+type FactorialLimitError struct {
+	Arg   float64
+	Limit float64
+}
+
+func (e *FactorialLimitError) Error() string {
+	return fmt.Sprintf("factorial argument %g exceeds limit %g", e.Arg, e.Limit)
+}
@@ -0,0 +1,363 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// opInfo describes how an infix operator binds
+type opInfo struct {
+	prec       int
+	rightAssoc bool
+	bitwise    bool // needs a domain with bitwise operators
+}
+
+// Precedence levels, from loosest to tightest:
+//
+//	logical or       ||       left-associative, short-circuit
+//	logical and      &&       left-associative, short-circuit
+//	logical not      not      not a == b is not (a == b)
+//	equality         == !=    left-associative
+//	relational       < <= > >=
+//	bitwise or       |        the bitwise operators bind tighter than
+//	bitwise xor      xor      comparisons, as in Python, so x & 0xF == 0
+//	bitwise and      &        tests the masked value, unlike in C
+//	shift            << >> >>>
+//	additive         + -      left-associative; 1 << 2 + 1 is 1 << 3
+//	uncertainty      ± +/-    a ± b + c ± d adds two uncertain values;
+//	                          2*x ± 0.1 is (2*x) ± 0.1
+//	multiplicative   * / %    left-associative; implicit multiplication
+//	                          (2x, 3(x+1), (a+b)(a-b), 2sin(x)) shares
+//	                          this level, so 1/2x is read as (1/2)*x
+//	unary prefix     - + ~    -2^2 is -(2^2)
+//	exponent         ^        right-associative: 2^3^2 is 2^(3^2)
+//	postfix          ! !!     2+3! is 2+(3!), 2^3! is 2^(3!); n!! is the
+//	                          double factorial
+const (
+	precOr = iota + 1
+	precAnd
+	precNot
+	precEquality
+	precRelational
+	precBitOr
+	precBitXor
+	precBitAnd
+	precShift
+	precAdditive
+	precUncertainty
+	precMultiplicative
+	precUnary
+	precExponent
+	precPostfix
+)
+
+// binaryOps are the infix operators
+var binaryOps = map[string]opInfo{
+	"||": {prec: precOr},
+	"&&": {prec: precAnd},
+	"==": {prec: precEquality}, "!=": {prec: precEquality},
+	"<": {prec: precRelational}, "<=": {prec: precRelational}, ">": {prec: precRelational}, ">=": {prec: precRelational},
+	"|": {prec: precBitOr, bitwise: true}, xorKeyword: {prec: precBitXor, bitwise: true}, "&": {prec: precBitAnd, bitwise: true},
+	"<<": {prec: precShift, bitwise: true}, ">>": {prec: precShift, bitwise: true}, ">>>": {prec: precShift, bitwise: true},
+	"+": {prec: precAdditive}, "-": {prec: precAdditive},
+	"±": {prec: precUncertainty}, plusMinusASCII: {prec: precUncertainty},
+	"*": {prec: precMultiplicative}, "/": {prec: precMultiplicative}, "%": {prec: precMultiplicative},
+	"^": {prec: precExponent, rightAssoc: true},
+}
+
+// implicitOp is how juxtaposed operands are combined
+var implicitOp = opInfo{prec: precMultiplicative}
+
+const (
+	// notKeyword is the word form of logical negation
+	notKeyword = "not"
+	// xorKeyword is bitwise exclusive or; ^ is already exponentiation
+	xorKeyword = "xor"
+)
+
+// Warning flags input that parses but may not mean what the user intended
+type Warning struct {
+	Pos     int
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("at position %d: %s", w.Pos+1, w.Message)
+}
+
+// Parse converts an expression into an AST using precedence climbing
+func Parse(input string) (Node, error) {
+	node, _, err := parse(input, DefaultLimits)
+	return node, err
+}
+
+// parse builds the AST and collects warnings about ambiguous input
+func parse(input string, limits Limits) (Node, []Warning, error) {
+	if limits.MaxInputLength > 0 && len(input) > limits.MaxInputLength {
+		return nil, nil, spanError(limits.MaxInputLength, len(input), &InputLengthError{
+			Length: len(input),
+			Limit:  limits.MaxInputLength,
+		})
+	}
+
+	tokens, err := Tokenize(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ps := &exprParser{tokens: tokens, maxDepth: limits.MaxDepth}
+	node, err := ps.parseExpr(0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch tok := ps.peek(); tok.Kind {
+	case TokenEOF:
+		return node, ps.warnings, nil
+	case TokenRParen:
+		return nil, nil, tokenError(tok, "unmatched closing parenthesis")
+	case TokenRBracket:
+		return nil, nil, tokenError(tok, "unmatched closing bracket")
+	default:
+		return nil, nil, tokenError(tok, "unexpected %s: %s", tok.Kind, tok.Text)
+	}
+}
+
+type exprParser struct {
+	tokens   []Token
+	pos      int
+	warnings []Warning
+
+	depth    int
+	maxDepth int
+}
+
+func (ps *exprParser) peek() Token {
+	return ps.tokens[ps.pos]
+}
+
+func (ps *exprParser) advance() Token {
+	tok := ps.tokens[ps.pos]
+	if tok.Kind != TokenEOF {
+		ps.pos++
+	}
+	return tok
+}
+
+// parseExpr parses operators that bind at least as tightly as minPrec
+func (ps *exprParser) parseExpr(minPrec int) (Node, error) {
+	ps.depth++
+	defer func() { ps.depth-- }()
+
+	if ps.maxDepth > 0 && ps.depth > ps.maxDepth {
+		tok := ps.peek()
+		return nil, spanError(tok.Pos, tok.Pos+len(tok.Text), &DepthLimitError{Limit: ps.maxDepth})
+	}
+
+	left, err := ps.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	divided := "" // last operator applied at this level, if it was / or %
+
+	for {
+		tok := ps.peek()
+
+		// An identifier, '(' or '[' directly after an operand is an implicit
+		// multiplication: 2x, 2sin(x), 3(x+1), (a+b)(a-b), 2[1, 2]
+		keyword := tok.Kind == TokenIdent && (tok.Text == notKeyword || tok.Text == xorKeyword)
+		if (tok.Kind == TokenIdent && !keyword) || tok.Kind == TokenLParen || tok.Kind == TokenLBracket {
+			if implicitOp.prec < minPrec {
+				return left, nil
+			}
+			if divided != "" {
+				ps.warnings = append(ps.warnings, Warning{
+					Pos:     tok.Pos,
+					Message: fmt.Sprintf("implicit multiplication after %s is read as (a%sb)*c; add parentheses to make the intent explicit", divided, divided),
+				})
+			}
+
+			right, err := ps.parseExpr(implicitOp.prec + 1)
+			if err != nil {
+				return nil, err
+			}
+			left = &BinaryExpr{X: left, OpPos: tok.Pos, Op: "*", Y: right, Implicit: true}
+			divided = ""
+			continue
+		}
+
+		if tok.Kind != TokenOperator && !keyword {
+			return left, nil
+		}
+
+		if tok.Text == "!" {
+			if precPostfix < minPrec {
+				return left, nil
+			}
+			ps.advance()
+
+			// Two adjacent ! are the double factorial; (n!)! needs parentheses
+			op := "!"
+			if next := ps.peek(); next.Text == "!" && next.Pos == tok.Pos+1 {
+				ps.advance()
+				op = "!!"
+			}
+			left = &PostfixExpr{X: left, OpPos: tok.Pos, Op: op}
+			continue
+		}
+
+		info, ok := binaryOps[tok.Text]
+		if !ok || info.prec < minPrec {
+			return left, nil
+		}
+		ps.advance()
+
+		nextMin := info.prec + 1
+		if info.rightAssoc {
+			nextMin = info.prec
+		}
+
+		right, err := ps.parseExpr(nextMin)
+		if err != nil {
+			return nil, err
+		}
+		op := tok.Text
+		if op == plusMinusASCII {
+			op = "±"
+		}
+		left = &BinaryExpr{X: left, OpPos: tok.Pos, Op: op, Y: right}
+
+		divided = ""
+		if tok.Text == "/" || tok.Text == "%" {
+			divided = tok.Text
+		}
+	}
+}
+
+func (ps *exprParser) parseUnary() (Node, error) {
+	tok := ps.peek()
+	if tok.Kind == TokenOperator && (tok.Text == "-" || tok.Text == "+" || tok.Text == "~") {
+		ps.advance()
+		operand, err := ps.parseExpr(precUnary)
+		if err != nil {
+			return nil, err
+		}
+		if tok.Text == "+" {
+			// Unary plus can be ignored
+			return operand, nil
+		}
+		return &UnaryExpr{OpPos: tok.Pos, Op: tok.Text, X: operand}, nil
+	}
+	if tok.Kind == TokenIdent && tok.Text == notKeyword {
+		ps.advance()
+		operand, err := ps.parseExpr(precNot)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{OpPos: tok.Pos, Op: notKeyword, X: operand}, nil
+	}
+	return ps.parsePrimary()
+}
+
+func (ps *exprParser) parsePrimary() (Node, error) {
+	tok := ps.advance()
+
+	switch tok.Kind {
+	case TokenNumber:
+		return &NumberLit{ValuePos: tok.Pos, Text: tok.Text, Value: tok.Value}, nil
+
+	case TokenString:
+		return &StringLit{ValuePos: tok.Pos, Value: tok.Text[1 : len(tok.Text)-1]}, nil
+
+	case TokenIdent:
+		if ps.peek().Kind == TokenLParen {
+			return ps.parseCall(tok)
+		}
+		return &Ident{NamePos: tok.Pos, Name: tok.Text}, nil
+
+	case TokenLParen:
+		node, err := ps.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		switch next := ps.advance(); next.Kind {
+		case TokenRParen:
+			return &ParenExpr{Lparen: tok.Pos, X: node, Rparen: next.Pos}, nil
+		case TokenEOF:
+			return nil, tokenError(tok, "missing closing parenthesis")
+		default:
+			return nil, tokenError(next, "unexpected %s: %s", next.Kind, next.Text)
+		}
+
+	case TokenLBracket:
+		return ps.parseInterval(tok)
+
+	case TokenEOF:
+		return nil, errorf(tok.Pos, tok.Pos, "unexpected end of expression")
+
+	case TokenRParen:
+		return nil, tokenError(tok, "unmatched closing parenthesis")
+
+	default:
+		return nil, tokenError(tok, "unexpected %s: %s", tok.Kind, tok.Text)
+	}
+}
+
+func (ps *exprParser) parseCall(name Token) (Node, error) {
+	lparen := ps.advance()
+	call := &CallExpr{NamePos: name.Pos, Name: name.Text}
+
+	if tok := ps.peek(); tok.Kind == TokenRParen {
+		ps.advance()
+		call.Rparen = tok.Pos
+		return call, nil
+	}
+
+	for {
+		arg, err := ps.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		switch tok := ps.advance(); tok.Kind {
+		case TokenComma:
+			continue
+		case TokenRParen:
+			call.Rparen = tok.Pos
+			return call, nil
+		case TokenEOF:
+			return nil, tokenError(lparen, "missing closing parenthesis for %s", name.Text)
+		default:
+			return nil, tokenError(tok, "unexpected %s in arguments to %s: %s", tok.Kind, name.Text, tok.Text)
+		}
+	}
+}
+
+// parseInterval parses the bounds of an interval literal [lo, hi]
+func (ps *exprParser) parseInterval(lbrack Token) (Node, error) {
+	lo, err := ps.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	switch tok := ps.advance(); tok.Kind {
+	case TokenComma:
+	case TokenEOF:
+		return nil, tokenError(lbrack, "missing closing bracket")
+	default:
+		return nil, tokenError(tok, "expected ',' between interval bounds, found %s", tok.Text)
+	}
+
+	hi, err := ps.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	switch tok := ps.advance(); tok.Kind {
+	case TokenRBracket:
+		return &IntervalExpr{Lbrack: lbrack.Pos, Lo: lo, Hi: hi, Rbrack: tok.Pos}, nil
+	case TokenEOF:
+		return nil, tokenError(lbrack, "missing closing bracket")
+	default:
+		return nil, tokenError(tok, "expected ']' after interval bounds, found %s", tok.Text)
+	}
+}
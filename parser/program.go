@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// Program is a compiled expression. It is immutable once built, so a single
+// Program can be evaluated many times and shared across goroutines.
+type Program struct {
+	source   string
+	tree     Node
+	vars     []string
+	warnings []Warning
+	limits   Limits
+}
+
+// Compile parses an expression once so that it can be evaluated repeatedly
+// with Eval. Unknown functions are reported when the program is evaluated.
+func Compile(expr string) (*Program, error) {
+	return CompileWithLimits(expr, DefaultLimits)
+}
+
+// CompileWithLimits is like Compile but enforces limits while parsing and
+// in every later evaluation of the program
+func CompileWithLimits(expr string, limits Limits) (*Program, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, errorf(0, len(expr), "empty expression")
+	}
+
+	tree, warnings, err := parse(expr, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{
+		source:   expr,
+		tree:     tree,
+		vars:     freeVariables(tree),
+		warnings: warnings,
+		limits:   limits,
+	}, nil
+}
+
+// Eval evaluates the program in radians mode with float64 arithmetic,
+// resolving identifiers from vars before falling back to the built-in
+// constants
+func (prog *Program) Eval(vars map[string]float64) (float64, error) {
+	return prog.EvalContext(context.Background(), vars)
+}
+
+// EvalContext is like Eval but stops early when ctx is cancelled
+func (prog *Program) EvalContext(ctx context.Context, vars map[string]float64) (float64, error) {
+	values := make(map[string]calculator.Value, len(vars))
+	for name, val := range vars {
+		values[name] = calculator.Float(val)
+	}
+
+	result, err := prog.EvalIn(ctx, calculator.FloatDomain{}, values)
+	if err != nil {
+		return 0, err
+	}
+	return result.Float64(), nil
+}
+
+// EvalIn evaluates the program in radians mode using the arithmetic of
+// domain, e.g. a calculator.BigDomain for arbitrary precision
+func (prog *Program) EvalIn(ctx context.Context, domain calculator.Domain, vars map[string]calculator.Value) (calculator.Value, error) {
+	ev := &evaluator{
+		ctx:       ctx,
+		limits:    prog.limits,
+		ops:       new(int),
+		domain:    domain,
+		angleMode: "rad",
+		variables: vars,
+	}
+	return ev.run(prog.tree)
+}
+
+// Vars returns the sorted names of the free variables the program needs
+func (prog *Program) Vars() []string {
+	return append([]string(nil), prog.vars...)
+}
+
+// Warnings returns notes about ambiguous input found while compiling, such
+// as implicit multiplication after a division
+func (prog *Program) Warnings() []Warning {
+	return append([]Warning(nil), prog.warnings...)
+}
+
+// String returns the source text the program was compiled from
+func (prog *Program) String() string {
+	return prog.source
+}
+
+// freeVariables collects identifiers that are not built-in constants
+func freeVariables(tree Node) []string {
+	seen := make(map[string]bool)
+
+	var walk func(Node)
+	walk = func(node Node) {
+		switch n := node.(type) {
+		case *Ident:
+			if !builtinConstants[strings.ToLower(n.Name)] {
+				seen[n.Name] = true
+			}
+		case *UnaryExpr:
+			walk(n.X)
+		case *ParenExpr:
+			walk(n.X)
+		case *BinaryExpr:
+			walk(n.X)
+			walk(n.Y)
+		case *PostfixExpr:
+			walk(n.X)
+		case *IntervalExpr:
+			walk(n.Lo)
+			walk(n.Hi)
+		case *CallExpr:
+			// x(y) is an implicit multiplication when x is not a function
+			if len(n.Args) == 1 && !calculator.IsFunction(n.Name) {
+				if !builtinConstants[strings.ToLower(n.Name)] {
+					seen[n.Name] = true
+				}
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		}
+	}
+	walk(tree)
+
+	vars := make([]string, 0, len(seen))
+	for name := range seen {
+		vars = append(vars, name)
+	}
+	sort.Strings(vars)
+	return vars
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// TestSpecialValues checks that both policies apply alike to operators and
+// functions in every domain whose numbers can be NaN or infinite
+func TestSpecialValues(t *testing.T) {
+	domains := []calculator.Domain{
+		calculator.FloatDomain{},
+		calculator.ComplexDomain{},
+		calculator.UncertainDomain{},
+		calculator.SigFigDomain{},
+	}
+	cases := []struct {
+		Expr string
+		Want string // the value under the IEEE policy
+	}{
+		{"1/0", "+Inf"},
+		{"-1/0", "-Inf"},
+		{"0/0", "NaN"},
+		{"exp(1000)", "+Inf"},
+		{"log(0)", "-Inf"},
+		{"(-1)!!", "1"},
+		{"(-3)!!", "NaN"},
+		{"5 % 0", "NaN"},
+	}
+	realCases := []struct {
+		Expr string
+		Want string
+	}{
+		{"sqrt(-1)", "NaN"},
+		{"log(-1)", "NaN"},
+		{"asin(2)", "NaN"},
+		{"acos(-2)", "NaN"},
+	}
+
+	for _, domain := range domains {
+		all := cases
+		if domain.Name() != "complex" {
+			all = append(all, realCases...)
+		}
+		for _, c := range all {
+			value, err := evalSpecial(domain, "ieee", c.Expr)
+			if err != nil {
+				t.Errorf("%s in %s mode under ieee: %v", c.Expr, domain.Name(), err)
+			} else if got := calculator.Float(value.Float64()).String(); got != c.Want {
+				t.Errorf("%s in %s mode under ieee = %s, want %s", c.Expr, domain.Name(), got, c.Want)
+			}
+
+			value, err = evalSpecial(domain, "strict", c.Expr)
+			if c.Want != "1" && err == nil {
+				t.Errorf("%s in %s mode under strict = %v, want an error", c.Expr, domain.Name(), value)
+			}
+		}
+	}
+}
+
+// TestStrictExactDomains checks that the domains without NaN or infinity
+// report them as errors whatever the policy
+func TestStrictExactDomains(t *testing.T) {
+	domains := []calculator.Domain{
+		calculator.NewBigDomain(20),
+		calculator.RatDomain{},
+		calculator.IntervalDomain{},
+		calculator.IntegerDomain{},
+		calculator.ProgrammerDomain{Bits: 32},
+	}
+	for _, domain := range domains {
+		for _, expr := range []string{"1/0", "sqrt(-1)"} {
+			if value, err := evalSpecial(domain, "ieee", expr); err == nil {
+				t.Errorf("%s in %s mode under ieee = %v, want an error", expr, domain.Name(), value)
+			}
+		}
+	}
+}
+
+// evalSpecial evaluates expr in a fresh environment with the given domain
+// and policy
+func evalSpecial(domain calculator.Domain, policy, expr string) (calculator.Value, error) {
+	env := NewEnvironment(NewLibrary())
+	env.SetDomain(domain)
+	env.SetSpecialValues(policy)
+	value, _, err := env.Evaluate(context.Background(), expr)
+	return value, err
+}
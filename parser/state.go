@@ -0,0 +1,256 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+	"github.com/Oluwaseyi89/calculator-built-with-go/utils"
+)
+
+// State is the part of an Environment that can be saved and restored:
+// variables and user-defined functions. Variables are saved as float64,
+// except complex values, which are saved in rectangular form, e.g. "3+4i",
+// intervals, which are saved as "[lo, hi]", values with an uncertainty,
+// which are saved as "x ± sigma", measurements from sig-fig mode, which
+// keep their significant figures, and NaN and infinities, which JSON
+// numbers cannot hold and are saved as "nan", "inf" or "-inf". Exact
+// integers are saved as decimal strings and fractions as "num/den", so that
+// no digit is lost, and big-mode values as their shortest decimal at their
+// precision. Restored uncertain values are independent of each other, even
+// if they were computed from the same measurement.
+type State struct {
+	Variables map[string]float64           `json:"variables"`
+	Complex   map[string]string            `json:"complex,omitempty"`
+	Intervals map[string]string            `json:"intervals,omitempty"`
+	Uncertain map[string]string            `json:"uncertain,omitempty"`
+	SigFigs   map[string]calculator.SigFig `json:"sigfigs,omitempty"`
+	Special   map[string]string            `json:"special,omitempty"`
+	Integers  map[string]string            `json:"integers,omitempty"`
+	Rationals map[string]string            `json:"rationals,omitempty"`
+	BigFloats map[string]SavedBigFloat     `json:"bigfloats,omitempty"`
+	Functions []*UserFunction              `json:"functions"`
+}
+
+// SavedBigFloat is a value from big mode as it is saved: the shortest
+// decimal that reads back as the same number at its precision, the
+// precision in bits and the significant digits it is shown with
+type SavedBigFloat struct {
+	Value  string `json:"value"`
+	Prec   uint   `json:"prec"`
+	Digits int    `json:"digits"`
+}
+
+// NumVariables returns the number of saved variables of every kind
+func (s State) NumVariables() int {
+	return len(s.Variables) + len(s.Complex) + len(s.Intervals) + len(s.Uncertain) + len(s.SigFigs) + len(s.Special) + len(s.Integers) + len(s.Rationals) + len(s.BigFloats)
+}
+
+// State returns a snapshot of the environment's variables and the functions
+// defined in it. Library functions are not included.
+func (env *Environment) State() State {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	vars := make(map[string]float64, len(env.variables))
+	var complexVars, intervalVars, uncertainVars, specialVars, integerVars, ratVars map[string]string
+	var sigFigVars map[string]calculator.SigFig
+	var bigVars map[string]SavedBigFloat
+	for name, value := range env.variables {
+		if z, ok := value.(calculator.Complex); ok && !z.IsReal() {
+			if complexVars == nil {
+				complexVars = make(map[string]string)
+			}
+			complexVars[name] = z.String()
+			continue
+		}
+		if x, ok := value.(calculator.Interval); ok && !x.IsPoint() {
+			if intervalVars == nil {
+				intervalVars = make(map[string]string)
+			}
+			intervalVars[name] = x.String()
+			continue
+		}
+		if u, ok := value.(calculator.Uncertain); ok && !u.IsExact() {
+			if uncertainVars == nil {
+				uncertainVars = make(map[string]string)
+			}
+			uncertainVars[name] = u.String()
+			continue
+		}
+		if n, ok := value.(calculator.Integer); ok {
+			if integerVars == nil {
+				integerVars = make(map[string]string)
+			}
+			integerVars[name] = n.String()
+			continue
+		}
+		if r, ok := value.(calculator.Rational); ok {
+			if ratVars == nil {
+				ratVars = make(map[string]string)
+			}
+			ratVars[name] = r.Rat().String()
+			continue
+		}
+		if x, ok := value.(calculator.BigFloat); ok {
+			if bigVars == nil {
+				bigVars = make(map[string]SavedBigFloat)
+			}
+			f := x.Big()
+			bigVars[name] = SavedBigFloat{Value: f.Text('g', -1), Prec: f.Prec(), Digits: x.Digits()}
+			continue
+		}
+		if f := value.Float64(); math.IsNaN(f) || math.IsInf(f, 0) {
+			if specialVars == nil {
+				specialVars = make(map[string]string)
+			}
+			specialVars[name] = utils.FormatNumber(f)
+			continue
+		}
+		if x, ok := value.(calculator.SigFig); ok && !x.IsExact() {
+			if sigFigVars == nil {
+				sigFigVars = make(map[string]calculator.SigFig)
+			}
+			sigFigVars[name] = x
+			continue
+		}
+		vars[name] = value.Float64()
+	}
+	return State{
+		Variables: vars,
+		Complex:   complexVars,
+		Intervals: intervalVars,
+		Uncertain: uncertainVars,
+		SigFigs:   sigFigVars,
+		Special:   specialVars,
+		Integers:  integerVars,
+		Rationals: ratVars,
+		BigFloats: bigVars,
+		Functions: sortedFunctions(env.functions),
+	}
+}
+
+// Restore replaces the environment's variables and functions with those in
+// s. Nothing is changed if any entry is invalid.
+func (env *Environment) Restore(s State) error {
+	vars := make(map[string]calculator.Value, len(s.Variables))
+	for name, value := range s.Variables {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		vars[name] = calculator.Float(value)
+	}
+	for name, text := range s.Complex {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		re, im, err := utils.ParseComplexNumber(text)
+		if err == nil {
+			err = utils.ValidateComplexNumber(re, im)
+		}
+		if err != nil {
+			return fmt.Errorf("variable %s: %v", name, err)
+		}
+		vars[name] = calculator.Complex(complex(re, im))
+	}
+	for name, text := range s.Intervals {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		x, err := calculator.ParseInterval(text)
+		if err != nil {
+			return fmt.Errorf("variable %s: %v", name, err)
+		}
+		vars[name] = x
+	}
+	for name, text := range s.Uncertain {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		u, err := calculator.ParseUncertain(text)
+		if err != nil {
+			return fmt.Errorf("variable %s: %v", name, err)
+		}
+		vars[name] = u
+	}
+	for name, x := range s.SigFigs {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		if x.Figures < 0 {
+			return fmt.Errorf("variable %s: invalid number of significant figures %d", name, x.Figures)
+		}
+		vars[name] = x
+	}
+	for name, text := range s.Special {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		x, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("variable %s: invalid number %q", name, text)
+		}
+		vars[name] = calculator.Float(x)
+	}
+	for name, text := range s.Integers {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		n, ok := new(big.Int).SetString(text, 10)
+		if !ok {
+			return fmt.Errorf("variable %s: invalid integer %q", name, text)
+		}
+		vars[name] = calculator.NewInteger(n)
+	}
+	for name, text := range s.Rationals {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		r, ok := new(big.Rat).SetString(text)
+		if !ok {
+			return fmt.Errorf("variable %s: invalid fraction %q", name, text)
+		}
+		vars[name] = calculator.NewRational(r)
+	}
+	for name, saved := range s.BigFloats {
+		if !calculator.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name: %s", name)
+		}
+		if saved.Prec == 0 || saved.Prec > big.MaxPrec || saved.Digits < 1 {
+			return fmt.Errorf("variable %s: invalid precision %d bits, %d digits", name, saved.Prec, saved.Digits)
+		}
+		x, _, err := big.ParseFloat(saved.Value, 10, saved.Prec, big.ToNearestEven)
+		if err != nil {
+			return fmt.Errorf("variable %s: invalid number %q", name, saved.Value)
+		}
+		vars[name] = calculator.NewBigFloat(x, saved.Digits)
+	}
+
+	funcs := make(map[string]*UserFunction, len(s.Functions))
+	for _, def := range s.Functions {
+		fn, err := newUserFunction(def.Name, def.Params, def.Body)
+		if err != nil {
+			return fmt.Errorf("function %s: %v", def.Name, err)
+		}
+		funcs[fn.Name] = fn
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.variables = vars
+	env.functions = funcs
+	return nil
+}
+
+// State returns a snapshot of the parser's variables and functions
+func (p *Parser) State() State {
+	return p.env.State()
+}
+
+// Restore replaces the parser's variables and functions with those in s.
+// Nothing is changed if any entry is invalid.
+func (p *Parser) Restore(s State) error {
+	return p.env.Restore(s)
+}
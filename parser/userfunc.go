@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
+)
+
+// DefaultMaxRecursion bounds how deeply user-defined functions may call
+// each other before evaluation is aborted
+const DefaultMaxRecursion = 256
+
+// UserFunction is a function defined at runtime, e.g. f(x, y) = x^2 + y^2
+type UserFunction struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+	Body   string   `json:"body"`
+
+	prog *Program
+}
+
+// String renders the function in the same form accepted by Define
+func (f *UserFunction) String() string {
+	return fmt.Sprintf("%s(%s) = %s", f.Name, strings.Join(f.Params, ", "), f.Body)
+}
+
+// Define parses a definition of the form "name(params) = body" and stores it
+func (p *Parser) Define(definition string) error {
+	return p.env.Define(definition)
+}
+
+// DefineFunction compiles body and stores it as a callable function. A
+// function may call itself or other user-defined functions.
+func (p *Parser) DefineFunction(name string, params []string, body string) error {
+	return p.env.DefineFunction(name, params, body)
+}
+
+// GetFunction returns the user-defined function with the given name
+func (p *Parser) GetFunction(name string) (*UserFunction, bool) {
+	return p.env.GetFunction(name)
+}
+
+// Functions returns the user-defined functions sorted by name
+func (p *Parser) Functions() []*UserFunction {
+	return p.env.Functions()
+}
+
+// DeleteFunction removes a user-defined function, reporting whether it existed
+func (p *Parser) DeleteFunction(name string) bool {
+	return p.env.DeleteFunction(name)
+}
+
+// ClearFunctions removes all user-defined functions
+func (p *Parser) ClearFunctions() {
+	p.env.ClearFunctions()
+}
+
+// SetMaxRecursion sets the maximum call depth for user-defined functions
+func (p *Parser) SetMaxRecursion(depth int) {
+	if depth <= 0 {
+		return
+	}
+	limits := p.env.Limits()
+	limits.MaxRecursion = depth
+	p.env.SetLimits(limits)
+}
+
+// ParseDefinition splits "name(a, b) = body" into its parts
+func ParseDefinition(definition string) (string, []string, string, error) {
+	head, body, found := strings.Cut(definition, "=")
+	if !found {
+		return "", nil, "", fmt.Errorf("function definition must have the form name(params) = expression")
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", nil, "", fmt.Errorf("function body is empty")
+	}
+
+	tokens, err := Tokenize(head)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(tokens) < 4 || tokens[0].Kind != TokenIdent || tokens[1].Kind != TokenLParen {
+		return "", nil, "", fmt.Errorf("function definition must have the form name(params) = expression")
+	}
+
+	name := tokens[0].Text
+	var params []string
+	rest := tokens[2:]
+
+	if rest[0].Kind == TokenRParen {
+		rest = rest[1:]
+	} else {
+		for {
+			if rest[0].Kind != TokenIdent {
+				return "", nil, "", fmt.Errorf("expected parameter name, found %s", rest[0].Kind)
+			}
+			params = append(params, rest[0].Text)
+
+			switch rest[1].Kind {
+			case TokenComma:
+				rest = rest[2:]
+				continue
+			case TokenRParen:
+				rest = rest[2:]
+			default:
+				return "", nil, "", fmt.Errorf("expected ',' or ')' in parameter list, found %s", rest[1].Kind)
+			}
+			break
+		}
+	}
+
+	if rest[0].Kind != TokenEOF {
+		return "", nil, "", fmt.Errorf("unexpected %s after parameter list", rest[0].Kind)
+	}
+
+	return name, params, body, nil
+}
+
+func newUserFunction(name string, params []string, body string) (*UserFunction, error) {
+	if !calculator.IsValidVariableName(name) {
+		return nil, fmt.Errorf("invalid function name: %s", name)
+	}
+
+	seen := make(map[string]bool, len(params))
+	for _, param := range params {
+		if !calculator.IsValidVariableName(param) {
+			return nil, fmt.Errorf("invalid parameter name: %s", param)
+		}
+		if seen[param] {
+			return nil, fmt.Errorf("duplicate parameter: %s", param)
+		}
+		seen[param] = true
+	}
+
+	prog, err := Compile(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserFunction{
+		Name:   name,
+		Params: append([]string(nil), params...),
+		Body:   body,
+		prog:   prog,
+	}, nil
+}
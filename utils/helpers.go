@@ -3,13 +3,24 @@ package utils
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// FormatNumber formats a float64 with appropriate precision
+// FormatNumber formats a float64 with appropriate precision. NaN and the
+// infinities are shown as nan, inf and -inf.
 func FormatNumber(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "nan"
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	}
+
 	// Use scientific notation for very large or small numbers
 	if math.Abs(value) >= 1e12 || (math.Abs(value) < 1e-6 && value != 0) {
 		return fmt.Sprintf("%.6e", value)
@@ -25,6 +36,20 @@ func FormatNumber(value float64) string {
 	}
 }
 
+// radixPrefixes are the literal prefixes of the bases that have one
+var radixPrefixes = map[int]string{2: "0b", 8: "0o", 16: "0x"}
+
+// FormatInteger formats n in base 2, 8, 10 or 16 with the prefix that
+// programmer mode reads back, e.g. 0xFF or -0b101
+func FormatInteger(n *big.Int, base int) string {
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+	}
+	digits := strings.ToUpper(new(big.Int).Abs(n).Text(base))
+	return sign + radixPrefixes[base] + digits
+}
+
 // DegreesToRadians converts degrees to radians
 func DegreesToRadians(degrees float64) float64 {
 	return degrees * math.Pi / 180
@@ -87,48 +112,51 @@ func TruncateString(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
-// ParseComplexNumber parses complex numbers (for future enhancement)
+// ParseComplexNumber parses a complex number written as a, bi, a+bi or a-bi,
+// e.g. "3-4i", "-2.5i", "i" or "1e-05+2i"
 func ParseComplexNumber(s string) (float64, float64, error) {
 	s = strings.ReplaceAll(s, " ", "")
 
-	if !strings.Contains(s, "i") {
+	if !strings.HasSuffix(s, "i") {
 		real, err := strconv.ParseFloat(s, 64)
-		return real, 0, err
-	}
-
-	// Simple complex number parser: a+bi or a-bi
-	s = strings.ReplaceAll(s, "i", "")
-	parts := strings.Split(s, "+")
-	if len(parts) == 2 {
-		real, err1 := strconv.ParseFloat(parts[0], 64)
-		imag, err2 := strconv.ParseFloat(parts[1], 64)
-
-		if err1 != nil || err2 != nil {
+		if err != nil {
 			return 0, 0, fmt.Errorf("invalid complex number format")
 		}
-		return real, imag, nil
+		return real, 0, nil
 	}
-
-	parts = strings.Split(s, "-")
-	if len(parts) == 3 { // Case: a-b-c (negative real and imaginary)
-		real, err1 := strconv.ParseFloat("-"+parts[1], 64)
-		imag, err2 := strconv.ParseFloat("-"+parts[2], 64)
-
-		if err1 != nil || err2 != nil {
-			return 0, 0, fmt.Errorf("invalid complex number format")
+	s = strings.TrimSuffix(s, "i")
+
+	// The imaginary part starts at the last sign that is not part of an
+	// exponent such as 1e-05
+	split := 0
+	for i := len(s) - 1; i > 0; i-- {
+		if (s[i] == '+' || s[i] == '-') && s[i-1] != 'e' && s[i-1] != 'E' {
+			split = i
+			break
 		}
-		return real, imag, nil
-	} else if len(parts) == 2 && s[0] != '-' { // Case: a-b (positive real, negative imaginary)
-		real, err1 := strconv.ParseFloat(parts[0], 64)
-		imag, err2 := strconv.ParseFloat("-"+parts[1], 64)
+	}
 
-		if err1 != nil || err2 != nil {
+	real := 0.0
+	if split > 0 {
+		var err error
+		real, err = strconv.ParseFloat(s[:split], 64)
+		if err != nil {
 			return 0, 0, fmt.Errorf("invalid complex number format")
 		}
-		return real, imag, nil
 	}
 
-	return 0, 0, fmt.Errorf("invalid complex number format")
+	imagText := s[split:]
+	switch imagText {
+	case "", "+":
+		return real, 1, nil
+	case "-":
+		return real, -1, nil
+	}
+	imag, err := strconv.ParseFloat(imagText, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid complex number format")
+	}
+	return real, imag, nil
 }
 
 // FormatComplex formats complex numbers as strings
@@ -136,6 +164,9 @@ func FormatComplex(real, imag float64) string {
 	if imag == 0 {
 		return FormatNumber(real)
 	}
+	if real == 0 {
+		return FormatNumber(imag) + "i"
+	}
 
 	op := "+"
 	if imag < 0 {
@@ -146,19 +177,184 @@ func FormatComplex(real, imag float64) string {
 	return fmt.Sprintf("%s %s %si", FormatNumber(real), op, FormatNumber(imag))
 }
 
-// GCD computes greatest common divisor (useful for fraction reduction)
+// FormatInterval formats an interval as [lo, hi], or as mid ± rad when
+// midrad is set. Bounds are rounded outward rather than to nearest, so the
+// printed interval still contains the exact one.
+func FormatInterval(lo, hi float64, midrad bool) string {
+	if !midrad {
+		return "[" + FormatBound(lo, false) + ", " + FormatBound(hi, true) + "]"
+	}
+
+	mid := FormatNumber(lo/2 + hi/2)
+	m, ok := new(big.Rat).SetString(mid)
+	if !ok || math.IsInf(lo, 0) || math.IsInf(hi, 0) {
+		return "[" + FormatBound(lo, false) + ", " + FormatBound(hi, true) + "]"
+	}
+	// The radius is measured from the midpoint as printed
+	rad := new(big.Rat).Sub(new(big.Rat).SetFloat64(hi), m)
+	if below := new(big.Rat).Sub(m, new(big.Rat).SetFloat64(lo)); below.Cmp(rad) > 0 {
+		rad = below
+	}
+	return mid + " ± " + formatRat(rad, true)
+}
+
+// FormatBound formats value like FormatNumber, but rounds up or down
+// instead of to nearest
+func FormatBound(value float64, up bool) string {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return FormatNumber(value)
+	}
+	return formatRat(new(big.Rat).SetFloat64(value), up)
+}
+
+// formatRat formats r with the layout of FormatNumber, rounding up or down
+func formatRat(r *big.Rat, up bool) string {
+	value, _ := r.Float64()
+	abs := math.Abs(value)
+
+	// Scientific notation keeps 7 significant digits
+	if abs >= 1e12 || (abs < 1e-6 && value != 0) {
+		exp := int(math.Floor(math.Log10(abs)))
+		for {
+			m := roundRat(r, 6-exp, up)
+			digits := new(big.Int).Abs(m).String()
+			switch {
+			case len(digits) > 7:
+				exp++
+			case len(digits) < 7 && m.Sign() != 0:
+				exp--
+			default:
+				sign := ""
+				if m.Sign() < 0 {
+					sign = "-"
+				}
+				return fmt.Sprintf("%s%s.%se%+03d", sign, digits[:1], digits[1:], exp)
+			}
+		}
+	}
+
+	decimals := 6
+	if abs >= 1000 {
+		decimals = 2
+	} else if abs >= 1 {
+		decimals = 4
+	}
+	m := roundRat(r, decimals, up)
+
+	sign := ""
+	if m.Sign() < 0 {
+		sign = "-"
+	}
+	digits := new(big.Int).Abs(m).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+// roundRat returns r·10^shift rounded up or down to an integer
+func roundRat(r *big.Rat, shift int, up bool) *big.Int {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(shift, -shift))), nil)
+	x := new(big.Rat).Set(r)
+	if shift >= 0 {
+		x.Mul(x, new(big.Rat).SetInt(scale))
+	} else {
+		x.Quo(x, new(big.Rat).SetInt(scale))
+	}
+
+	// Euclidean division by a positive denominator rounds down
+	q := new(big.Int).Div(x.Num(), x.Denom())
+	if up && !x.IsInt() {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// FormatUncertain formats a value with its standard uncertainty as
+// x ± sigma. sigma is rounded to one significant figure, or two when its
+// first digit is 1, and x is rounded to the same decimal place. Very large
+// and very small values share an exponent: (1.23 ± 0.05)e+08.
+func FormatUncertain(x, sigma float64) string {
+	if sigma == 0 {
+		return FormatNumber(x)
+	}
+	if math.IsInf(sigma, 0) || math.IsNaN(sigma) || math.IsInf(x, 0) || math.IsNaN(x) {
+		return FormatNumber(x) + " ± " + FormatNumber(sigma)
+	}
+
+	figures := 1
+	if strconv.FormatFloat(sigma, 'e', 1, 64)[0] == '1' {
+		figures = 2
+	}
+	// place is the decimal exponent of the last digit shown
+	place := decimalExponent(sigma, figures) - (figures - 1)
+
+	lead := decimalExponent(math.Max(math.Abs(x), sigma), 1)
+	if lead >= 6 || place < -6 {
+		scale := math.Pow(10, float64(lead))
+		decimals := max(lead-place, 0)
+		return fmt.Sprintf("(%s ± %.*f)e%+03d", fixed(x/scale, decimals), decimals, sigma/scale, lead)
+	}
+
+	if place > 0 {
+		// Round to tens, hundreds, ... and show no decimals
+		scale := math.Pow(10, float64(place))
+		return fixed(math.Round(x/scale)*scale, 0) + " ± " + fixed(math.Round(sigma/scale)*scale, 0)
+	}
+	return fixed(x, -place) + " ± " + fixed(sigma, -place)
+}
+
+// decimalExponent returns the power of ten of the first digit of x after
+// rounding it to the given number of significant figures
+func decimalExponent(x float64, figures int) int {
+	text := strconv.FormatFloat(x, 'e', figures-1, 64)
+	exp, _ := strconv.Atoi(text[strings.IndexByte(text, 'e')+1:])
+	return exp
+}
+
+// fixed formats x with the given number of decimals, without the sign of a
+// value that rounds to zero
+func fixed(x float64, decimals int) string {
+	text := strconv.FormatFloat(x, 'f', decimals, 64)
+	if strings.Trim(text, "-0.") == "" {
+		return strings.TrimPrefix(text, "-")
+	}
+	return text
+}
+
+// GCD computes greatest common divisor (useful for fraction reduction).
+// The result is never negative.
 func GCD(a, b int) int {
 	for b != 0 {
 		a, b = b, a%b
 	}
+	if a < 0 {
+		return -a
+	}
 	return a
 }
 
-// LCM computes least common multiple
+// LCM computes least common multiple. The result overflows silently when it
+// does not fit in an int; use CheckedLCM to detect that.
 func LCM(a, b int) int {
 	return a * b / GCD(a, b)
 }
 
+// CheckedLCM computes least common multiple, or returns an error if it does
+// not fit in an int. The lcm expression function works on integers of any
+// size.
+func CheckedLCM(a, b int) (int, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	n := big.NewInt(int64(a / GCD(a, b)))
+	n.Abs(n.Mul(n, big.NewInt(int64(b))))
+	if !n.IsInt64() || n.Int64() > math.MaxInt {
+		return 0, fmt.Errorf("lcm(%d, %d) overflows int", a, b)
+	}
+	return int(n.Int64()), nil
+}
+
 // ConvertToFraction converts decimal to fraction approximation
 func ConvertToFraction(decimal float64, maxDenominator int) (int, int) {
 	// Using continued fraction approximation
@@ -208,7 +404,8 @@ var FactorialTable = []float64{
 	3628800, // 10!
 }
 
-// GetFactorial returns factorial with caching
+// GetFactorial returns n! rounded to float64. Small factorials come from
+// FactorialTable and larger ones are rounded once from the exact product.
 func GetFactorial(n int) float64 {
 	if n < 0 {
 		return math.NaN()
@@ -217,32 +414,16 @@ func GetFactorial(n int) float64 {
 	if n < len(FactorialTable) {
 		return FactorialTable[n]
 	}
-
-	// Compute recursively
-	result := FactorialTable[len(FactorialTable)-1]
-	for i := len(FactorialTable); i <= n; i++ {
-		result *= float64(i)
+	if n > 170 { // 170! is close to max float64
+		return math.Inf(1)
 	}
 
+	result, _ := new(big.Float).SetInt(new(big.Int).MulRange(1, int64(n))).Float64()
 	return result
 }
 
-// IsPrime checks if a number is prime
+// IsPrime checks if a number is prime. The test is exact for every int,
+// including ones too large for trial division.
 func IsPrime(n int) bool {
-	if n < 2 {
-		return false
-	}
-	if n == 2 || n == 3 {
-		return true
-	}
-	if n%2 == 0 || n%3 == 0 {
-		return false
-	}
-
-	for i := 5; i*i <= n; i += 6 {
-		if n%i == 0 || n%(i+2) == 0 {
-			return false
-		}
-	}
-	return true
+	return n >= 2 && big.NewInt(int64(n)).ProbablyPrime(0)
 }
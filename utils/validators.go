@@ -5,6 +5,8 @@ import (
 	"math"
 	"regexp"
 	"strings"
+
+	"github.com/Oluwaseyi89/calculator-built-with-go/calculator"
 )
 
 var (
@@ -19,9 +21,15 @@ var (
 
 	// ValidIdentifierRegex validates variable/constant names
 	ValidIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	functionCallRegex = regexp.MustCompile(`([a-z_][a-z0-9_]*)\(`)
 )
 
-// ValidateExpression validates a mathematical expression
+// ValidateExpression validates the syntax of a mathematical expression.
+//
+// Deprecated: the calculator no longer calls it. Its character check only
+// knows the original operators; use the parser, which reports errors with
+// their position, to validate input.
 func ValidateExpression(expr string) error {
 	if expr == "" {
 		return fmt.Errorf("empty expression")
@@ -42,14 +50,6 @@ func ValidateExpression(expr string) error {
 		return fmt.Errorf("invalid characters in expression")
 	}
 
-	// Check for division by zero patterns
-	if strings.Contains(expr, "/0") && !strings.Contains(expr, "/0.") {
-		// Allow /0. as in 5/0.5
-		if !strings.Contains(expr, "/0.") {
-			return fmt.Errorf("potential division by zero")
-		}
-	}
-
 	// Check for invalid function calls
 	if err := ValidateFunctionCalls(expr); err != nil {
 		return err
@@ -97,33 +97,19 @@ func HasConsecutiveOperators(expr string) bool {
 	return false
 }
 
-// ValidateFunctionCalls validates function syntax
+// ValidateFunctionCalls returns an error naming the first function called
+// in expr that is not registered.
+//
+// Deprecated: the parser reports unknown functions with their position.
 func ValidateFunctionCalls(expr string) error {
-	// Find all function calls
-	funcPattern := regexp.MustCompile(`([a-z]+)\(`)
-	matches := funcPattern.FindAllStringSubmatch(expr, -1)
-
-	validFunctions := map[string]bool{
-		"sin": true, "cos": true, "tan": true,
-		"asin": true, "acos": true, "atan": true,
-		"sinh": true, "cosh": true, "tanh": true,
-		"sqrt": true, "cbrt": true,
-		"log": true, "log10": true,
-		"exp": true, "abs": true,
-	}
-
-	for _, match := range matches {
-		funcName := match[1]
-		if !validFunctions[funcName] {
-			return fmt.Errorf("unknown function: %s", funcName)
+	for _, match := range functionCallRegex.FindAllStringSubmatch(expr, -1) {
+		if !calculator.IsFunction(match[1]) {
+			return fmt.Errorf("unknown function: %s", match[1])
 		}
 	}
-
-	// Check for missing closing parentheses
 	if strings.Count(expr, "(") != strings.Count(expr, ")") {
 		return fmt.Errorf("mismatched parentheses")
 	}
-
 	return nil
 }
 
@@ -197,13 +183,26 @@ func ValidateSqrtArgument(x float64) error {
 	return nil
 }
 
-// ValidateFactorialArgument validates argument for factorial
+// ValidateFactorialArgument validates the argument of a float64 factorial.
+// Non-integers are accepted, since x! is Γ(x+1).
 func ValidateFactorialArgument(x float64) error {
-	if x < 0 || x != math.Trunc(x) {
-		return fmt.Errorf("factorial undefined for non-integer or negative numbers")
+	if x < 0 && x == math.Trunc(x) {
+		return fmt.Errorf("factorial undefined for negative integers")
 	}
 	if x > 170 { // 170! is close to max float64
-		return fmt.Errorf("factorial too large for calculation")
+		return fmt.Errorf("factorial too large for float64; use big or rational mode for every digit")
+	}
+	return nil
+}
+
+// ValidateDoubleFactorialArgument validates the argument of a float64
+// double factorial
+func ValidateDoubleFactorialArgument(x float64) error {
+	if x < -1 || x != math.Trunc(x) {
+		return fmt.Errorf("double factorial undefined for non-integers and integers below -1")
+	}
+	if x > 300 { // 300!! is close to max float64
+		return fmt.Errorf("double factorial too large for float64; use big or rational mode for every digit")
 	}
 	return nil
 }
@@ -222,10 +221,10 @@ func ValidateDivision(numerator, denominator float64) error {
 	return nil
 }
 
-// ValidatePower validates power operation
+// ValidatePower validates power operation. 0^0 is 1, as in math.Pow.
 func ValidatePower(base, exponent float64) error {
-	if base == 0 && exponent <= 0 {
-		return fmt.Errorf("0^0 or 0^(negative) is undefined")
+	if base == 0 && exponent < 0 {
+		return fmt.Errorf("0 to a negative power is infinite")
 	}
 	if base < 0 && exponent != math.Trunc(exponent) {
 		return fmt.Errorf("negative base with non-integer exponent")
@@ -233,27 +232,18 @@ func ValidatePower(base, exponent float64) error {
 	return nil
 }
 
-// IsValidVariableName checks if a string is a valid variable name
+// IsValidVariableName checks if a string is a valid variable name.
+//
+// Deprecated: use calculator.IsValidVariableName.
 func IsValidVariableName(name string) bool {
-	return ValidIdentifierRegex.MatchString(name) && !IsReservedKeyword(name)
+	return calculator.IsValidVariableName(name)
 }
 
-// IsReservedKeyword checks if a string is a reserved keyword
+// IsReservedKeyword checks if a string is a reserved keyword.
+//
+// Deprecated: use calculator.IsReservedKeyword.
 func IsReservedKeyword(s string) bool {
-	reserved := map[string]bool{
-		"pi": true, "e": true, "ans": true,
-		"sin": true, "cos": true, "tan": true,
-		"asin": true, "acos": true, "atan": true,
-		"sinh": true, "cosh": true, "tanh": true,
-		"sqrt": true, "cbrt": true,
-		"log": true, "log10": true,
-		"exp": true, "abs": true,
-		"exit": true, "quit": true,
-		"help": true, "clear": true,
-		"mem": true, "history": true,
-	}
-
-	return reserved[strings.ToLower(s)]
+	return calculator.IsReservedKeyword(s)
 }
 
 // ValidateRange checks if a value is within specified range